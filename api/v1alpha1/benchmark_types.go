@@ -19,6 +19,7 @@ package v1alpha1
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 type HttpPost struct {
@@ -35,24 +36,222 @@ type Output struct {
 	HttpPostSpec HttpPost `json:"httpPostSpec"`
 }
 
+// Intervals are either an integer number of seconds or a string that can be
+// parsed by time.ParseDuration, e.g. "250ms"
 type ConstantIncreaseDecreaseRate struct {
-	IncInterval int `json:"incInterval"`
-	DecInterval int `json:"decInterval"`
-	Max         int `json:"max"`
-	Min         int `json:"min"`
+	IncInterval intstr.IntOrString `json:"incInterval"`
+	DecInterval intstr.IntOrString `json:"decInterval"`
+	Max         int                `json:"max"`
+	Min         int                `json:"min"`
 }
 
+// Interval is either an integer number of seconds or a string that can be
+// parsed by time.ParseDuration, e.g. "250ms"
 type ConstantRate struct {
-	Interval int `json:"interval"`
+	Interval intstr.IntOrString `json:"interval"`
+}
+
+// Rate whose firings follow a Poisson process, i.e. the time between firings
+// is exponentially distributed.
+type PoissonRate struct {
+	// Mean time between firings, either an integer number of seconds or a
+	// string that can be parsed by time.ParseDuration
+	Interval intstr.IntOrString `json:"interval"`
+
+	// Seed for the random number generator, so that runs can be reproduced.
+	// If not set, the generator is seeded from the current time.
+	// +optional
+	// +nullable
+	Seed int64 `json:"seed"`
+}
+
+// Rate that replays a recorded schedule of firings.  The schedule is a list of
+// offsets, in seconds, from the start of the trace.
+type TraceRate struct {
+	// Name of the ConfigMap in the library namespace that holds the trace
+	ConfigMapName string `json:"configMapName"`
+
+	// Key of the trace in the ConfigMap's data.  May be omitted if the
+	// ConfigMap only has one key.
+	// +optional
+	// +nullable
+	Key string `json:"key"`
+
+	// Format of the trace, either "csv" or "json".  A csv trace has one
+	// offset per line (only the first column is used, and a header line is
+	// skipped), a json trace is an array of offsets.  If not set, the format
+	// is guessed from the trace's contents.
+	// +optional
+	// +nullable
+	Format string `json:"format"`
+
+	// Factor that every offset is multiplied by, e.g. "0.5" replays the
+	// trace twice as fast.  Defaults to "1".
+	// +optional
+	// +nullable
+	TimeScale string `json:"timeScale"`
+
+	// If true, the trace starts over once its last firing has happened
+	// +optional
+	// +nullable
+	Loop bool `json:"loop"`
+
+	// Time from the start of one pass through a looping trace to the start
+	// of the next, scaled by timeScale like the offsets.  Must be a string
+	// that can be parsed by time.ParseDuration, and at least as long as the
+	// last offset.  Defaults to the last offset, in which case an offset of
+	// 0 fires only once at each loop boundary.
+	// +optional
+	// +nullable
+	LoopPeriod string `json:"loopPeriod"`
+}
+
+// One stage of a ScheduleRate.  During a stage the rate either fires every
+// interval, or fires once at the start of the stage with concurrency as the
+// rate's counter value.  A stage with neither set doesn't fire at all, e.g.
+// for a cooldown period.
+type ScheduleStage struct {
+	// How long the stage lasts.  Must be a string that can be parsed by
+	// time.ParseDuration.
+	Duration string `json:"duration"`
+
+	// Time between firings during this stage, either an integer number of
+	// seconds or a string that can be parsed by time.ParseDuration
+	// +optional
+	// +nullable
+	Interval intstr.IntOrString `json:"interval"`
+
+	// Counter value for the duration of this stage
+	// +optional
+	// +nullable
+	Concurrency int `json:"concurrency"`
+}
+
+// Rate made up of a sequence of stages, which are run in order.  Once the
+// last stage is over the rate stops firing.
+type ScheduleRate struct {
+	Stages []ScheduleStage `json:"stages"`
+}
+
+// One schedule of a CronRate
+type CronEntry struct {
+	// Identifies the entry in the rateFired metric.  Defaults to the
+	// schedule itself.
+	// +optional
+	// +nullable
+	Name string `json:"name"`
+
+	// Standard 5-field cron expression: minute, hour, day of month, month and
+	// day of week, e.g. "15 * * * *"
+	Schedule string `json:"schedule"`
+}
+
+// Rate that fires whenever one of its cron schedules matches, in the
+// controller's time zone
+type CronRate struct {
+	Entries []CronEntry `json:"entries"`
+}
+
+// Rate that fires when something happens in the benchmark, rather than on a
+// timer.  Only one of the fields should be set.
+type EventRate struct {
+	// Fire each time a pod of the named workload reaches the Succeeded phase
+	// +optional
+	// +nullable
+	WorkloadName string `json:"workloadName"`
+
+	// Fire each time a PVC created for the named volume becomes Bound
+	// +optional
+	// +nullable
+	VolumeName string `json:"volumeName"`
+
+	// Fire each time the named control operation completes, i.e. once the
+	// objects it created are ready and the objects it deleted are gone
+	// +optional
+	// +nullable
+	ControlOperationName string `json:"controlOperationName"`
+}
+
+// Rate that keeps a fixed number of firings in flight: it fires again only once
+// the control operations from an earlier firing have completed, i.e. the
+// volumes or snapshots they created are ready to use and the objects they
+// deleted are gone
+type ClosedLoopRate struct {
+	// Number of firings whose control operations are in flight at once
+	// +kubebuilder:validation:Minimum=1
+	Concurrency int `json:"concurrency"`
+
+	// Time to wait after a firing's control operations complete before
+	// firing again.  Must be a string that can be parsed by
+	// time.ParseDuration.
+	// +optional
+	// +nullable
+	ThinkTime string `json:"thinkTime"`
+}
+
+// What a rate does with a firing when the control operations it drives are
+// still running from the previous firing
+type Backpressure struct {
+	// One of "drop" (discard the firing), "queue" (hold on to up to
+	// queueLength firings and run them in order once the control operations
+	// finish) or "coalesce" (hold on to only the most recent firing)
+	// +kubebuilder:validation:Enum=drop;queue;coalesce
+	// +kubebuilder:default:=drop
+	// +optional
+	// +nullable
+	Policy string `json:"policy"`
+
+	// Maximum number of firings held on to by the queue policy.  Firings
+	// beyond this are dropped.
+	// +kubebuilder:default:=10
+	// +optional
+	// +nullable
+	QueueLength int `json:"queueLength"`
 }
 
 type Rate struct {
 	Name string `json:"name"`
 
+	// Time to wait before the rate starts firing, measured from when the
+	// workloads finish initializing.  Must be a string that can be parsed by
+	// time.ParseDuration.
+	// +optional
+	// +nullable
+	StartAfter string `json:"startAfter"`
+
+	// Time after which the rate stops firing, measured from when the
+	// workloads finish initializing.  Must be a string that can be parsed by
+	// time.ParseDuration.
+	// +optional
+	// +nullable
+	StopAfter string `json:"stopAfter"`
+
+	// Maximum number of times the rate fires.  Unlimited if not set.
+	// +optional
+	// +nullable
+	MaxFirings int `json:"maxFirings"`
+
+	// What to do with firings while the control operations are still
+	// running.  Event rates ignore this and never drop firings.
+	// +optional
+	Backpressure *Backpressure `json:"backpressure,omitempty"`
+
 	// +optional
 	ConstantRateSpec ConstantRate `json:"constantRateSpec,omitempty"`
 	// +optional
 	ConstantIncreaseDecreaseRateSpec ConstantIncreaseDecreaseRate `json:"constantIncreaseDecreaseRateSpec,omitempty"`
+	// +optional
+	PoissonRateSpec PoissonRate `json:"poissonRateSpec,omitempty"`
+	// +optional
+	TraceRateSpec TraceRate `json:"traceRateSpec,omitempty"`
+	// +optional
+	ScheduleRateSpec *ScheduleRate `json:"scheduleRateSpec,omitempty"`
+	// +optional
+	CronRateSpec *CronRate `json:"cronRateSpec,omitempty"`
+	// +optional
+	EventRateSpec EventRate `json:"eventRateSpec,omitempty"`
+	// +optional
+	ClosedLoopRateSpec *ClosedLoopRate `json:"closedLoopRateSpec,omitempty"`
 }
 
 // Snapshots and deletions can operate on an individual object or a selector
@@ -69,27 +268,347 @@ type Snapshot struct {
 	VolumeName string `json:"volumeName"`
 
 	SnapshotClass string `json:"snapshotClass"`
+
+	// Snapshot all of the workload's volumes as one group: quiesce the
+	// workload, snapshot every volume, then resume it.  Requires
+	// workloadName.
+	// +optional
+	// +nullable
+	GroupSnapshot bool `json:"groupSnapshot"`
+
+	// How to quiesce the workload for a group snapshot: "none", "exec" to
+	// run quiesceCommand in the workload's pods, or "scale" to scale the
+	// workload to zero replicas
+	// +kubebuilder:validation:Enum=none;exec;scale
+	// +kubebuilder:default:=none
+	// +optional
+	// +nullable
+	Quiesce string `json:"quiesce,omitempty"`
+
+	// Command run in each of the workload's pods to quiesce it, e.g. to
+	// flush and freeze writes, when quiesce is "exec"
+	// +optional
+	// +nullable
+	QuiesceCommand []string `json:"quiesceCommand"`
+
+	// Command run in each of the workload's pods to resume it after the
+	// snapshots are taken, when quiesce is "exec"
+	// +optional
+	// +nullable
+	ResumeCommand []string `json:"resumeCommand"`
 }
 
+// Grows volumes.  Exactly one of workloadName or volumeName must be set, and
+// only one of size or percent.
+type Resize struct {
+	// Resize the volumes of the named workload
+	// +optional
+	// +nullable
+	WorkloadName string `json:"workloadName"`
+
+	// Resize the volumes created for the named volume
+	// +optional
+	// +nullable
+	VolumeName string `json:"volumeName"`
+
+	// Size to grow each volume to, e.g. "20Gi".  Volumes that are already at
+	// least this big are left alone.
+	// +optional
+	// +nullable
+	Size string `json:"size"`
+
+	// Percentage to grow each volume's current size by
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	// +nullable
+	Percent int `json:"percent"`
+}
+
+// Creates volumes from the snapshots taken by a snapshot control operation
+type Restore struct {
+	// Name of the snapshot control operation whose snapshots are restored
+	SnapshotName string `json:"snapshotName"`
+
+	// Which snapshots to restore: "newest", "oldest" or "random".  Only
+	// snapshots that are ready to use are considered.
+	// +kubebuilder:validation:Enum=newest;oldest;random
+	// +kubebuilder:default:=newest
+	// +optional
+	// +nullable
+	Policy string `json:"policy"`
+
+	// Number of snapshots to restore each time the operation runs
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default:=1
+	// +optional
+	// +nullable
+	Count int `json:"count"`
+
+	// StorageClass of the restored volumes.  Defaults to the StorageClass of
+	// the volume the snapshot was taken of.
+	// +optional
+	// +nullable
+	StorageClassName string `json:"storageClassName"`
+
+	// Workload from the library to run against each restored volume, e.g. to
+	// check its contents.  The name of the restored PVC is passed to the
+	// workload in the volname variable.
+	// +optional
+	// +nullable
+	VerifyWorkload string `json:"verifyWorkload"`
+
+	// Variables for the verification workload
+	// +optional
+	// +nullable
+	VerifyVars map[string]string `json:"verifyVars"`
+}
+
+// Creates copies of volumes using CSI volume cloning.  Exactly one of
+// workloadName or volumeName must be set.
+type Clone struct {
+	// Clone the volumes of the named workload
+	// +optional
+	// +nullable
+	WorkloadName string `json:"workloadName"`
+
+	// Clone the volumes created for the named volume
+	// +optional
+	// +nullable
+	VolumeName string `json:"volumeName"`
+
+	// StorageClass of the clones.  Defaults to the StorageClass of the volume
+	// being cloned, which most drivers require.
+	// +optional
+	// +nullable
+	StorageClassName string `json:"storageClassName"`
+}
+
+// Chooses which of the candidates a control operation could act on (e.g. a
+// workload's running pods) it acts on each time it runs
+type Selection struct {
+	// Which of the candidates to act on: "oldest", "newest", "random" or
+	// "all".  The default depends on the operation.
+	// +kubebuilder:validation:Enum=oldest;newest;random;all
+	// +optional
+	// +nullable
+	Policy string `json:"policy,omitempty"`
+
+	// Number of candidates to act on each time the operation runs.  Ignored
+	// by the "all" policy.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default:=1
+	// +optional
+	// +nullable
+	Count int `json:"count,omitempty"`
+
+	// Seed for the "random" policy.  If not set, the seed is taken from the
+	// current time.
+	// +optional
+	// +nullable
+	Seed int64 `json:"seed"`
+}
+
+// Kills running pods of a workload, to measure how quickly their volumes are
+// reattached and the workload recovers.  The pods to kill are picked by
+// policy, which defaults to "random".
+type PodKill struct {
+	WorkloadName string `json:"workloadName"`
+
+	// Only kill pods with this role, e.g. "workload".  Pods with any role
+	// are considered if not set.
+	// +optional
+	// +nullable
+	Role string `json:"role"`
+
+	// Seconds the pods are given to shut down.  0 kills them immediately.
+	// Defaults to the pods' own grace period.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	// +nullable
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds"`
+
+	Selection `json:",inline"`
+}
+
+// Cordons and drains the nodes running some of a workload's pods, forcing the
+// pods and their volumes to move to other nodes.  The pods whose nodes are
+// drained are picked by policy, which defaults to "random".
+type Drain struct {
+	WorkloadName string `json:"workloadName"`
+
+	// Only consider pods with this role, e.g. "workload".  Pods with any
+	// role are considered if not set.
+	// +optional
+	// +nullable
+	Role string `json:"role"`
+
+	Selection `json:",inline"`
+
+	// How long after a node is cordoned it is uncordoned, e.g. "5m".  Nodes
+	// are left cordoned if not set.
+	// +optional
+	// +nullable
+	UncordonAfter string `json:"uncordonAfter"`
+}
+
+// Repeatedly attaches and detaches volumes by creating a short-lived pod that
+// mounts a volume, waiting for it to run, then deleting it.  Exactly one of
+// workloadName or volumeName must be set.  The bound volumes to mount are
+// picked by policy, which defaults to "random".
+type MountChurn struct {
+	// Mount the volumes of the named workload
+	// +optional
+	// +nullable
+	WorkloadName string `json:"workloadName"`
+
+	// Mount the volumes created for the named volume
+	// +optional
+	// +nullable
+	VolumeName string `json:"volumeName"`
+
+	// Image the mounting pod runs.  It must have a sleep command.  Defaults
+	// to busybox.
+	// +optional
+	// +nullable
+	Image string `json:"image"`
+
+	Selection `json:",inline"`
+}
+
+// Runs a command in running pods of a workload, e.g. to freeze a filesystem or
+// drop caches.  The command's exit code, output and duration are sent to the
+// operation's output.  The pods to run it in are picked by policy, which
+// defaults to "all".
+type Exec struct {
+	WorkloadName string `json:"workloadName"`
+
+	// Only run the command in pods with this role, e.g. "workload".  Pods
+	// with any role are considered if not set.
+	// +optional
+	// +nullable
+	Role string `json:"role"`
+
+	// Container to run the command in.  Defaults to the pod's first
+	// container.
+	// +optional
+	// +nullable
+	Container string `json:"container"`
+
+	// Command to run, which is not run in a shell.  To use shell features,
+	// run e.g. ["sh", "-c", "sync; echo 3 > /proc/sys/vm/drop_caches"].
+	// +kubebuilder:validation:MinItems=1
+	Command []string `json:"command"`
+
+	Selection `json:",inline"`
+
+	// How long to wait for the command to finish, e.g. "30s".  Defaults to
+	// 10 minutes.
+	// +optional
+	// +nullable
+	Timeout string `json:"timeout"`
+}
+
+// Creates an object from a template in the library, e.g. a StorageClass or a
+// custom resource of a storage operator.  The template is rendered with the
+// same variables as workloads, plus {{RATE_COUNTER}}, the value of the rate's
+// counter, and {{FIRING_NUM}}, the number of times the rate has fired.
+type Create struct {
+	// Name of the ConfigMap in the library namespace with the template
+	ConfigMapName string `json:"configMapName"`
+
+	// Key in the ConfigMap that holds the template
+	Key string `json:"key"`
+
+	// Values for the variables in the template.  Defaults are taken from
+	// the ConfigMap's cnsbench.default.<variable> annotations.
+	// +optional
+	// +nullable
+	Vars map[string]string `json:"vars"`
+}
+
+// Deletes objects matching a label selector, which must not be empty.  If
+// apiVersion and kind are set, only objects of that kind are considered.
+// Otherwise, every kind of namespaced object the API server knows about is
+// searched, including custom resources, optionally limited to the kinds listed
+// in kinds.  Kinds the controller isn't allowed to list are skipped.
 type Delete struct {
-	APIVersion string               `json:"apiVersion"`
-	Kind       string               `json:"kind"`
-	Selector   metav1.LabelSelector `json:"selector"`
+	// +optional
+	// +nullable
+	APIVersion string `json:"apiVersion"`
+	// +optional
+	// +nullable
+	Kind string `json:"kind"`
+
+	// Kinds to search when apiVersion and kind aren't set, e.g.
+	// "VolumeSnapshot" or "ReplicaSet".  Searches every kind if empty.
+	// +optional
+	// +nullable
+	Kinds []string `json:"kinds"`
+
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// Which of the matching objects to delete: "oldest", "newest",
+	// "random" or "all"
+	// +kubebuilder:validation:Enum=oldest;newest;random;all
+	// +kubebuilder:default:=oldest
+	// +optional
+	// +nullable
+	Policy string `json:"policy,omitempty"`
+
+	// Number of objects to delete each time the operation runs.  Ignored by
+	// the "all" policy.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default:=1
+	// +optional
+	// +nullable
+	Count int `json:"count,omitempty"`
+
+	// Seed for the "random" policy.  Runs with the same seed delete objects
+	// in the same order, by age (e.g. the third oldest, then the oldest).  If
+	// not set, the seed is taken from the current time.
+	// +optional
+	// +nullable
+	Seed int64 `json:"seed"`
 }
 
-// TODO: need a way of specifying how to scale - up or down, and by how much
+// Sets the number of replicas of an object, or of a workload's objects, to the
+// value of the rate's counter.  If scaleScripts is set, the scaling is done by
+// a script run in a pod; otherwise the object is scaled directly through its
+// scale subresource.
 type Scale struct {
 	// +optional
 	// +nullable
 	ObjName string `json:"objName"`
+
+	// API version of the object to scale.  Defaults to apps/v1.
+	// +optional
+	// +nullable
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the object to scale, e.g. Deployment, StatefulSet, ReplicaSet
+	// or any custom resource with a scale subresource.  Required unless
+	// scaleScripts is set.
+	// +optional
+	// +nullable
+	Kind string `json:"kind"`
+
+	// Name of a library ConfigMap with scripts that do the scaling, for
+	// objects that can't be scaled directly, e.g. ones managed by an
+	// operator
 	// +optional
 	// +nullable
 	ScaleScripts string `json:"scaleScripts"`
 
+	// Name of a workload whose objects should be scaled, instead of
+	// naming the object itself.  The object to scale and any scale scripts
+	// are looked up in the workload's library ConfigMap.
 	// +optional
 	// +nullable
 	WorkloadName string `json:"workloadName"`
 
+	// Service account the scale script pod runs as
+	// +optional
+	// +nullable
 	ServiceAccountName string `json:"serviceAccountName"`
 }
 
@@ -163,6 +682,16 @@ type Workload struct {
 	// +optional
 	// +nullable
 	RateName string `json:"rateName"`
+
+	// How the rate named by rateName drives the workload.  With "add", each
+	// firing starts count more instances.  With "target", the rate's
+	// counter value is the number of instances that should be running:
+	// instances are started or the newest ones stopped to match it.
+	// +kubebuilder:validation:Enum=add;target
+	// +kubebuilder:default:=add
+	// +optional
+	// +nullable
+	RateMode string `json:"rateMode,omitempty"`
 }
 
 type ControlOperation struct {
@@ -180,6 +709,38 @@ type ControlOperation struct {
 	// +nullable
 	DeleteSpec Delete `json:"deleteSpec"`
 
+	// +optional
+	// +nullable
+	ResizeSpec *Resize `json:"resizeSpec,omitempty"`
+
+	// +optional
+	// +nullable
+	RestoreSpec *Restore `json:"restoreSpec,omitempty"`
+
+	// +optional
+	// +nullable
+	CloneSpec *Clone `json:"cloneSpec,omitempty"`
+
+	// +optional
+	// +nullable
+	PodKillSpec *PodKill `json:"podKillSpec,omitempty"`
+
+	// +optional
+	// +nullable
+	DrainSpec *Drain `json:"drainSpec,omitempty"`
+
+	// +optional
+	// +nullable
+	MountChurnSpec *MountChurn `json:"mountChurnSpec,omitempty"`
+
+	// +optional
+	// +nullable
+	ExecSpec *Exec `json:"execSpec,omitempty"`
+
+	// +optional
+	// +nullable
+	CreateSpec Create `json:"createSpec"`
+
 	// +optional
 	// +nullable
 	Outputs ActionOutput `json:"outputs"`
@@ -241,6 +802,21 @@ const (
 	Initializing BenchmarkState = "Initializing"
 )
 
+type RateStatus struct {
+	Name string `json:"name"`
+
+	// Number of times the rate has fired
+	Firings int `json:"firings"`
+
+	// Number of firings that were discarded because the control operations
+	// were still running
+	Dropped int `json:"dropped"`
+
+	// Number of firings that were held on to until the control operations
+	// finished
+	Delayed int `json:"delayed"`
+}
+
 type BenchmarkCondition struct {
 	// +optional
 	// +nullable
@@ -285,6 +861,10 @@ type BenchmarkStatus struct {
 	RunningWorkloads int `json:"runningWorkloads"`
 	RunningRates     int `json:"runningRates"`
 
+	// +optional
+	// +nullable
+	Rates []RateStatus `json:"rates"`
+
 	Conditions []BenchmarkCondition `json:"conditions"`
 }
 
@@ -39,6 +39,21 @@ func (in *ActionOutput) DeepCopy() *ActionOutput {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Backpressure) DeepCopyInto(out *Backpressure) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Backpressure.
+func (in *Backpressure) DeepCopy() *Backpressure {
+	if in == nil {
+		return nil
+	}
+	out := new(Backpressure)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Benchmark) DeepCopyInto(out *Benchmark) {
 	*out = *in
@@ -142,7 +157,9 @@ func (in *BenchmarkSpec) DeepCopyInto(out *BenchmarkSpec) {
 	if in.Rates != nil {
 		in, out := &in.Rates, &out.Rates
 		*out = make([]Rate, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.Outputs != nil {
 		in, out := &in.Outputs, &out.Outputs
@@ -167,6 +184,11 @@ func (in *BenchmarkStatus) DeepCopyInto(out *BenchmarkStatus) {
 	in.CompletionTime.DeepCopyInto(&out.CompletionTime)
 	in.InitCompletionTime.DeepCopyInto(&out.InitCompletionTime)
 	in.TargetCompletionTime.DeepCopyInto(&out.TargetCompletionTime)
+	if in.Rates != nil {
+		in, out := &in.Rates, &out.Rates
+		*out = make([]RateStatus, len(*in))
+		copy(*out, *in)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]BenchmarkCondition, len(*in))
@@ -186,9 +208,41 @@ func (in *BenchmarkStatus) DeepCopy() *BenchmarkStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Clone) DeepCopyInto(out *Clone) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Clone.
+func (in *Clone) DeepCopy() *Clone {
+	if in == nil {
+		return nil
+	}
+	out := new(Clone)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClosedLoopRate) DeepCopyInto(out *ClosedLoopRate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClosedLoopRate.
+func (in *ClosedLoopRate) DeepCopy() *ClosedLoopRate {
+	if in == nil {
+		return nil
+	}
+	out := new(ClosedLoopRate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConstantIncreaseDecreaseRate) DeepCopyInto(out *ConstantIncreaseDecreaseRate) {
 	*out = *in
+	out.IncInterval = in.IncInterval
+	out.DecInterval = in.DecInterval
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConstantIncreaseDecreaseRate.
@@ -204,6 +258,7 @@ func (in *ConstantIncreaseDecreaseRate) DeepCopy() *ConstantIncreaseDecreaseRate
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConstantRate) DeepCopyInto(out *ConstantRate) {
 	*out = *in
+	out.Interval = in.Interval
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConstantRate.
@@ -219,9 +274,45 @@ func (in *ConstantRate) DeepCopy() *ConstantRate {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ControlOperation) DeepCopyInto(out *ControlOperation) {
 	*out = *in
-	out.SnapshotSpec = in.SnapshotSpec
+	in.SnapshotSpec.DeepCopyInto(&out.SnapshotSpec)
 	out.ScaleSpec = in.ScaleSpec
 	in.DeleteSpec.DeepCopyInto(&out.DeleteSpec)
+	if in.ResizeSpec != nil {
+		in, out := &in.ResizeSpec, &out.ResizeSpec
+		*out = new(Resize)
+		**out = **in
+	}
+	if in.RestoreSpec != nil {
+		in, out := &in.RestoreSpec, &out.RestoreSpec
+		*out = new(Restore)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CloneSpec != nil {
+		in, out := &in.CloneSpec, &out.CloneSpec
+		*out = new(Clone)
+		**out = **in
+	}
+	if in.PodKillSpec != nil {
+		in, out := &in.PodKillSpec, &out.PodKillSpec
+		*out = new(PodKill)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DrainSpec != nil {
+		in, out := &in.DrainSpec, &out.DrainSpec
+		*out = new(Drain)
+		**out = **in
+	}
+	if in.MountChurnSpec != nil {
+		in, out := &in.MountChurnSpec, &out.MountChurnSpec
+		*out = new(MountChurn)
+		**out = **in
+	}
+	if in.ExecSpec != nil {
+		in, out := &in.ExecSpec, &out.ExecSpec
+		*out = new(Exec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.CreateSpec.DeepCopyInto(&out.CreateSpec)
 	out.Outputs = in.Outputs
 }
 
@@ -235,9 +326,71 @@ func (in *ControlOperation) DeepCopy() *ControlOperation {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Create) DeepCopyInto(out *Create) {
+	*out = *in
+	if in.Vars != nil {
+		in, out := &in.Vars, &out.Vars
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Create.
+func (in *Create) DeepCopy() *Create {
+	if in == nil {
+		return nil
+	}
+	out := new(Create)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CronEntry) DeepCopyInto(out *CronEntry) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CronEntry.
+func (in *CronEntry) DeepCopy() *CronEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(CronEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CronRate) DeepCopyInto(out *CronRate) {
+	*out = *in
+	if in.Entries != nil {
+		in, out := &in.Entries, &out.Entries
+		*out = make([]CronEntry, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CronRate.
+func (in *CronRate) DeepCopy() *CronRate {
+	if in == nil {
+		return nil
+	}
+	out := new(CronRate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Delete) DeepCopyInto(out *Delete) {
 	*out = *in
+	if in.Kinds != nil {
+		in, out := &in.Kinds, &out.Kinds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	in.Selector.DeepCopyInto(&out.Selector)
 }
 
@@ -251,6 +404,58 @@ func (in *Delete) DeepCopy() *Delete {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Drain) DeepCopyInto(out *Drain) {
+	*out = *in
+	out.Selection = in.Selection
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Drain.
+func (in *Drain) DeepCopy() *Drain {
+	if in == nil {
+		return nil
+	}
+	out := new(Drain)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EventRate) DeepCopyInto(out *EventRate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventRate.
+func (in *EventRate) DeepCopy() *EventRate {
+	if in == nil {
+		return nil
+	}
+	out := new(EventRate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Exec) DeepCopyInto(out *Exec) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.Selection = in.Selection
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Exec.
+func (in *Exec) DeepCopy() *Exec {
+	if in == nil {
+		return nil
+	}
+	out := new(Exec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HttpPost) DeepCopyInto(out *HttpPost) {
 	*out = *in
@@ -266,6 +471,22 @@ func (in *HttpPost) DeepCopy() *HttpPost {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MountChurn) DeepCopyInto(out *MountChurn) {
+	*out = *in
+	out.Selection = in.Selection
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MountChurn.
+func (in *MountChurn) DeepCopy() *MountChurn {
+	if in == nil {
+		return nil
+	}
+	out := new(MountChurn)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Output) DeepCopyInto(out *Output) {
 	*out = *in
@@ -297,11 +518,71 @@ func (in *OutputFile) DeepCopy() *OutputFile {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodKill) DeepCopyInto(out *PodKill) {
+	*out = *in
+	if in.GracePeriodSeconds != nil {
+		in, out := &in.GracePeriodSeconds, &out.GracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	out.Selection = in.Selection
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodKill.
+func (in *PodKill) DeepCopy() *PodKill {
+	if in == nil {
+		return nil
+	}
+	out := new(PodKill)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PoissonRate) DeepCopyInto(out *PoissonRate) {
+	*out = *in
+	out.Interval = in.Interval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PoissonRate.
+func (in *PoissonRate) DeepCopy() *PoissonRate {
+	if in == nil {
+		return nil
+	}
+	out := new(PoissonRate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Rate) DeepCopyInto(out *Rate) {
 	*out = *in
+	if in.Backpressure != nil {
+		in, out := &in.Backpressure, &out.Backpressure
+		*out = new(Backpressure)
+		**out = **in
+	}
 	out.ConstantRateSpec = in.ConstantRateSpec
 	out.ConstantIncreaseDecreaseRateSpec = in.ConstantIncreaseDecreaseRateSpec
+	out.PoissonRateSpec = in.PoissonRateSpec
+	out.TraceRateSpec = in.TraceRateSpec
+	if in.ScheduleRateSpec != nil {
+		in, out := &in.ScheduleRateSpec, &out.ScheduleRateSpec
+		*out = new(ScheduleRate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CronRateSpec != nil {
+		in, out := &in.CronRateSpec, &out.CronRateSpec
+		*out = new(CronRate)
+		(*in).DeepCopyInto(*out)
+	}
+	out.EventRateSpec = in.EventRateSpec
+	if in.ClosedLoopRateSpec != nil {
+		in, out := &in.ClosedLoopRateSpec, &out.ClosedLoopRateSpec
+		*out = new(ClosedLoopRate)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Rate.
@@ -314,6 +595,58 @@ func (in *Rate) DeepCopy() *Rate {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateStatus) DeepCopyInto(out *RateStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateStatus.
+func (in *RateStatus) DeepCopy() *RateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Resize) DeepCopyInto(out *Resize) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Resize.
+func (in *Resize) DeepCopy() *Resize {
+	if in == nil {
+		return nil
+	}
+	out := new(Resize)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Restore) DeepCopyInto(out *Restore) {
+	*out = *in
+	if in.VerifyVars != nil {
+		in, out := &in.VerifyVars, &out.VerifyVars
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Restore.
+func (in *Restore) DeepCopy() *Restore {
+	if in == nil {
+		return nil
+	}
+	out := new(Restore)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Scale) DeepCopyInto(out *Scale) {
 	*out = *in
@@ -329,9 +662,70 @@ func (in *Scale) DeepCopy() *Scale {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduleRate) DeepCopyInto(out *ScheduleRate) {
+	*out = *in
+	if in.Stages != nil {
+		in, out := &in.Stages, &out.Stages
+		*out = make([]ScheduleStage, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleRate.
+func (in *ScheduleRate) DeepCopy() *ScheduleRate {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduleRate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduleStage) DeepCopyInto(out *ScheduleStage) {
+	*out = *in
+	out.Interval = in.Interval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleStage.
+func (in *ScheduleStage) DeepCopy() *ScheduleStage {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduleStage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Selection) DeepCopyInto(out *Selection) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Selection.
+func (in *Selection) DeepCopy() *Selection {
+	if in == nil {
+		return nil
+	}
+	out := new(Selection)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Snapshot) DeepCopyInto(out *Snapshot) {
 	*out = *in
+	if in.QuiesceCommand != nil {
+		in, out := &in.QuiesceCommand, &out.QuiesceCommand
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ResumeCommand != nil {
+		in, out := &in.ResumeCommand, &out.ResumeCommand
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Snapshot.
@@ -344,6 +738,21 @@ func (in *Snapshot) DeepCopy() *Snapshot {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TraceRate) DeepCopyInto(out *TraceRate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TraceRate.
+func (in *TraceRate) DeepCopy() *TraceRate {
+	if in == nil {
+		return nil
+	}
+	out := new(TraceRate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Volume) DeepCopyInto(out *Volume) {
 	*out = *in
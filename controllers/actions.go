@@ -3,9 +3,11 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	cnsbench "github.com/cnsbench/cnsbench/api/v1alpha1"
 
@@ -14,9 +16,15 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/apiserver/pkg/storage/names"
 	"k8s.io/client-go/kubernetes/scheme"
 	utilptr "k8s.io/utils/pointer"
@@ -79,15 +87,18 @@ func (r *BenchmarkReconciler) createObj(bm *cnsbench.Benchmark, obj client.Objec
 	return nil
 }
 
-func (r *BenchmarkReconciler) CreateVolume(bm *cnsbench.Benchmark, vol cnsbench.Volume) {
-	// XXX This might be called because a rate fired, in which case there might
-	// already be a volume - need to check what the last volume number is and
-	// count from <last vol> to count+<last vol>
+// Returns the PVCs that were created
+func (r *BenchmarkReconciler) CreateVolume(bm *cnsbench.Benchmark, vol cnsbench.Volume) []client.Object {
+	var created []client.Object
 	for c := 0; c < vol.Count; c++ {
 		name := vol.Name
 		if vol.Count > 1 {
 			name += "-" + strconv.Itoa(c)
 		}
+		// A rate fires more than once, so each firing needs new names
+		if vol.RateName != "" {
+			name = names.NameGenerator.GenerateName(names.SimpleNameGenerator, name+"-")
+		}
 		pvc := corev1.PersistentVolumeClaim{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      name,
@@ -101,11 +112,21 @@ func (r *BenchmarkReconciler) CreateVolume(bm *cnsbench.Benchmark, vol cnsbench.
 
 		if err := r.createObj(bm, client.Object(&pvc), true); err != nil {
 			r.Log.Error(err, "Creating volume")
+		} else {
+			created = append(created, &pvc)
 		}
 	}
+	return created
 }
 
 func (r *BenchmarkReconciler) RunWorkload(bm *cnsbench.Benchmark, a cnsbench.Workload, workloadName string) error {
+	r.instanceMutex.Lock()
+	defer r.instanceMutex.Unlock()
+	return r.runWorkload(bm, a, workloadName)
+}
+
+// Must be called with instanceMutex held
+func (r *BenchmarkReconciler) runWorkload(bm *cnsbench.Benchmark, a cnsbench.Workload, workloadName string) error {
 	cm := &corev1.ConfigMap{}
 	err := r.Client.Get(context.TODO(), client.ObjectKey{Name: a.Workload, Namespace: LIBRARY_NAMESPACE}, cm)
 	if err != nil {
@@ -141,84 +162,1024 @@ func (r *BenchmarkReconciler) RunWorkload(bm *cnsbench.Benchmark, a cnsbench.Wor
 	return nil
 }
 
-func (r *BenchmarkReconciler) CreateSnapshot(bm *cnsbench.Benchmark, s cnsbench.Snapshot, actionName string) error {
+// Returns the PVCs belonging to the named workload, or created for the named
+// volume.  If neither is given, returns every PVC.
+func (r *BenchmarkReconciler) selectPVCs(workloadName, volumeName string) (*corev1.PersistentVolumeClaimList, error) {
 	ls := &metav1.LabelSelector{}
 
-	if s.WorkloadName != "" {
-		ls = metav1.AddLabelToSelector(ls, "workloadname", s.WorkloadName)
-	} else if s.VolumeName != "" {
-		ls = metav1.AddLabelToSelector(ls, "volumename", s.VolumeName)
+	if workloadName != "" {
+		ls = metav1.AddLabelToSelector(ls, "workloadname", workloadName)
+	} else if volumeName != "" {
+		ls = metav1.AddLabelToSelector(ls, "volumename", volumeName)
 	}
 	selector, err := metav1.LabelSelectorAsSelector(ls)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	pvcs := &corev1.PersistentVolumeClaimList{}
 	if err := r.Client.List(context.TODO(), pvcs, &client.ListOptions{Namespace: "default", LabelSelector: selector}); err != nil {
-		return err
+		return nil, err
 	}
+	return pvcs, nil
+}
 
-	snapshotscheme.AddToScheme(scheme.Scheme)
+// Returns the VolumeSnapshots that were created.  Each snapshot is tracked in
+// the background until it is ready to use.
+func (r *BenchmarkReconciler) CreateSnapshot(bm *cnsbench.Benchmark, a cnsbench.ControlOperation) ([]client.Object, error) {
+	s := a.SnapshotSpec
+	if s.GroupSnapshot {
+		return r.CreateGroupSnapshot(bm, a)
+	}
+
+	pvcs, err := r.selectPVCs(s.WorkloadName, s.VolumeName)
+	if err != nil {
+		return nil, err
+	}
 
+	var created []client.Object
 	// Takes a snapshot of every volume matching the given selector
 	for _, pvc := range pvcs.Items {
-		volName := pvc.Name
-		name := names.NameGenerator.GenerateName(names.SimpleNameGenerator, bm.ObjectMeta.Name+"-snapshot-")
-		snap := snapshotv1beta1.VolumeSnapshot{
+		start := time.Now()
+		snap, err := r.snapshotPVC(bm, a, pvc.Name, nil)
+		if err != nil {
+			r.Log.Error(err, "Creating snapshot")
+			continue
+		}
+		go r.trackSnapshot(bm, a, snap.Name, pvc.Name, start)
+		created = append(created, snap)
+	}
+
+	return created, nil
+}
+
+// Takes a snapshot of a volume.  The snapshot is labelled with the operation's
+// name, and with any extra labels given.
+func (r *BenchmarkReconciler) snapshotPVC(bm *cnsbench.Benchmark, a cnsbench.ControlOperation, pvcName string, extraLabels map[string]string) (*snapshotv1beta1.VolumeSnapshot, error) {
+	snapshotscheme.AddToScheme(scheme.Scheme)
+
+	snapLabels := map[string]string{
+		"workloadname": a.Name,
+	}
+	for k, v := range extraLabels {
+		snapLabels[k] = v
+	}
+	snap := &snapshotv1beta1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      names.NameGenerator.GenerateName(names.SimpleNameGenerator, bm.ObjectMeta.Name+"-snapshot-"),
+			Namespace: "default",
+			Labels:    snapLabels,
+		},
+		Spec: snapshotv1beta1.VolumeSnapshotSpec{
+			VolumeSnapshotClassName: &a.SnapshotSpec.SnapshotClass,
+			Source: snapshotv1beta1.VolumeSnapshotSource{
+				PersistentVolumeClaimName: &pvcName,
+			},
+		},
+	}
+
+	if err := r.createObj(bm, client.Object(snap), false); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// Waits for a new snapshot to be ready to use and reports how long it took
+func (r *BenchmarkReconciler) trackSnapshot(bm *cnsbench.Benchmark, a cnsbench.ControlOperation, name, pvcName string, start time.Time, metrics ...string) {
+	latency, err := waitForOp(start, func() (bool, error) {
+		snap := &unstructured.Unstructured{}
+		snap.SetGroupVersionKind(snapshotv1beta1.SchemeGroupVersion.WithKind("VolumeSnapshot"))
+		if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: name, Namespace: "default"}, snap); err != nil {
+			return false, err
+		}
+		ready, _, _ := unstructured.NestedBool(snap.Object, "status", "readyToUse")
+		return ready, nil
+	})
+
+	metrics = append([]string{"name", name, "pvc", pvcName, "latencyMs", millis(latency)}, metrics...)
+	if err != nil {
+		r.Log.Error(err, "Waiting for snapshot to be ready", "name", name)
+		metrics = append(metrics, "error", err.Error())
+	}
+	r.opMetric(bm, a, "createSnapshot", metrics...)
+}
+
+// Grows every selected volume, returning the PVCs that were resized.  Each
+// expansion is tracked in the background until it completes.
+func (r *BenchmarkReconciler) ResizeVolume(bm *cnsbench.Benchmark, a cnsbench.ControlOperation) ([]client.Object, error) {
+	s := a.ResizeSpec
+	if s.WorkloadName == "" && s.VolumeName == "" {
+		return nil, fmt.Errorf("Resize must set either workloadName or volumeName")
+	}
+	var size resource.Quantity
+	if s.Size != "" {
+		var err error
+		if size, err = resource.ParseQuantity(s.Size); err != nil {
+			return nil, err
+		}
+	} else if s.Percent == 0 {
+		return nil, fmt.Errorf("Resize must set either size or percent")
+	}
+
+	pvcs, err := r.selectPVCs(s.WorkloadName, s.VolumeName)
+	if err != nil {
+		return nil, err
+	}
+
+	var resized []client.Object
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		oldSize := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		newSize := size
+		if s.Percent != 0 {
+			newSize = *resource.NewQuantity(oldSize.Value()*int64(100+s.Percent)/100, oldSize.Format)
+		}
+		if newSize.Cmp(oldSize) <= 0 {
+			r.Log.Info("Volume already big enough, not resizing", "name", pvc.Name, "size", oldSize.String())
+			continue
+		}
+
+		if pvc.Spec.Resources.Requests == nil {
+			pvc.Spec.Resources.Requests = make(corev1.ResourceList)
+		}
+		pvc.Spec.Resources.Requests[corev1.ResourceStorage] = newSize
+		start := time.Now()
+		if err := r.Client.Update(context.TODO(), pvc); err != nil {
+			return resized, err
+		}
+		r.Log.Info("Resizing volume", "name", pvc.Name, "from", oldSize.String(), "to", newSize.String())
+		go r.trackResize(bm, a, pvc.Name, oldSize, newSize, start)
+		resized = append(resized, pvc)
+	}
+
+	return resized, nil
+}
+
+// Waits for a volume expansion to finish and reports how long it took.
+// Expansion happens in two steps: the volume itself is grown, then, for
+// filesystem volumes, the filesystem is grown by the node the volume is
+// mounted on.  In between, the PVC has the FileSystemResizePending condition.
+func (r *BenchmarkReconciler) trackResize(bm *cnsbench.Benchmark, a cnsbench.ControlOperation, name string, from, to resource.Quantity, start time.Time) {
+	var volumeLatency time.Duration
+	latency, err := waitForOp(start, func() (bool, error) {
+		pvc := &corev1.PersistentVolumeClaim{}
+		if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: name, Namespace: "default"}, pvc); err != nil {
+			return false, err
+		}
+		fsResizePending := false
+		for _, c := range pvc.Status.Conditions {
+			if c.Type == corev1.PersistentVolumeClaimFileSystemResizePending && c.Status == corev1.ConditionTrue {
+				fsResizePending = true
+			}
+		}
+		capacity := pvc.Status.Capacity[corev1.ResourceStorage]
+		done := capacity.Cmp(to) >= 0 && !fsResizePending
+		if volumeLatency == 0 && (fsResizePending || done) {
+			volumeLatency = time.Since(start)
+		}
+		return done, nil
+	})
+
+	metrics := []string{"name", name, "from", from.String(), "to", to.String(), "latencyMs", millis(latency), "volumeLatencyMs", millis(volumeLatency)}
+	if err != nil {
+		r.Log.Error(err, "Waiting for volume to resize", "name", name)
+		metrics = append(metrics, "error", err.Error())
+	}
+	r.opMetric(bm, a, "resizeVolume", metrics...)
+}
+
+// Returns policy, or def if policy isn't set.  Control operations that pick
+// objects with a cnsbench.Selection each have their own default policy.
+func policyOr(policy, def string) string {
+	if policy == "" {
+		return def
+	}
+	return policy
+}
+
+// Orders objs according to policy ("oldest", "newest", "random" or "all") and
+// returns the first count of them, or all of them for the "all" policy
+func pickObjs(objs []unstructured.Unstructured, policy string, count int, rng *rand.Rand) []unstructured.Unstructured {
+	// Start from a fixed order, oldest first, so that a shuffle with the
+	// same seed picks objects the same way regardless of the order they
+	// were listed in.  Names break ties, since creation times only have a
+	// resolution of seconds.
+	sort.Slice(objs, func(i, j int) bool {
+		ti, tj := objs[i].GetCreationTimestamp().Time, objs[j].GetCreationTimestamp().Time
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return objs[i].GetName() < objs[j].GetName()
+	})
+	switch policy {
+	case "random":
+		rng.Shuffle(len(objs), func(i, j int) { objs[i], objs[j] = objs[j], objs[i] })
+	case "newest":
+		for i, j := 0, len(objs)-1; i < j; i, j = i+1, j-1 {
+			objs[i], objs[j] = objs[j], objs[i]
+		}
+	}
+	if count == 0 {
+		count = 1
+	}
+	if policy != "all" && count < len(objs) {
+		objs = objs[:count]
+	}
+	return objs
+}
+
+// Creates PVCs from the snapshots taken by a snapshot control operation,
+// returning the PVCs that were created
+func (r *BenchmarkReconciler) RestoreSnapshot(bm *cnsbench.Benchmark, a cnsbench.ControlOperation) ([]client.Object, error) {
+	s := a.RestoreSpec
+	ls := &metav1.LabelSelector{}
+	ls = metav1.AddLabelToSelector(ls, "workloadname", s.SnapshotName)
+	selector, err := metav1.LabelSelectorAsSelector(ls)
+	if err != nil {
+		return nil, err
+	}
+	snapList := &unstructured.UnstructuredList{}
+	snapList.SetGroupVersionKind(snapshotv1beta1.SchemeGroupVersion.WithKind("VolumeSnapshotList"))
+	if err := r.Client.List(context.TODO(), snapList, &client.ListOptions{Namespace: "default", LabelSelector: selector}); err != nil {
+		return nil, err
+	}
+	var ready []unstructured.Unstructured
+	for _, snap := range snapList.Items {
+		if isReady, _, _ := unstructured.NestedBool(snap.Object, "status", "readyToUse"); isReady {
+			ready = append(ready, snap)
+		}
+	}
+	if len(ready) == 0 {
+		r.Log.Info("No snapshots ready to restore", "snapshotName", s.SnapshotName)
+		return nil, nil
+	}
+
+	var created []client.Object
+	for _, snap := range pickObjs(ready, s.Policy, s.Count, r.opRNG(bm, a.Name, 0)) {
+		pvc, err := r.restorePVC(bm, s, a.Name, snap)
+		if err != nil {
+			r.Log.Error(err, "Restoring snapshot", "snapshot", snap.GetName())
+			continue
+		}
+		start := time.Now()
+		if err := r.createObj(bm, client.Object(pvc), true); err != nil {
+			r.Log.Error(err, "Creating restored volume")
+			continue
+		}
+		go r.trackBind(bm, a, "restoreVolume", pvc.Name, start, "snapshot", snap.GetName())
+		created = append(created, pvc)
+
+		if s.VerifyWorkload != "" {
+			vars := map[string]string{}
+			for k, v := range s.VerifyVars {
+				vars[k] = v
+			}
+			vars["volname"] = pvc.Name
+			verify := cnsbench.Workload{Name: a.Name + "-verify", Workload: s.VerifyWorkload, Vars: vars, Count: 1}
+			if err := r.RunWorkload(bm, verify, verify.Name); err != nil {
+				r.Log.Error(err, "Running verification workload", "volume", pvc.Name)
+			}
+		}
+	}
+
+	return created, nil
+}
+
+// Builds a PVC that restores the given snapshot.  Whatever isn't set in the
+// Restore spec is taken from the snapshot or from the PVC it was taken of.
+func (r *BenchmarkReconciler) restorePVC(bm *cnsbench.Benchmark, s *cnsbench.Restore, actionName string, snap unstructured.Unstructured) (*corev1.PersistentVolumeClaim, error) {
+	spec := corev1.PersistentVolumeClaimSpec{
+		AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+		Resources:   corev1.ResourceRequirements{Requests: corev1.ResourceList{}},
+	}
+	if sourceName, _, _ := unstructured.NestedString(snap.Object, "spec", "source", "persistentVolumeClaimName"); sourceName != "" {
+		source := &corev1.PersistentVolumeClaim{}
+		if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: sourceName, Namespace: "default"}, source); err == nil {
+			spec.AccessModes = source.Spec.AccessModes
+			spec.StorageClassName = source.Spec.StorageClassName
+			spec.VolumeMode = source.Spec.VolumeMode
+			spec.Resources.Requests[corev1.ResourceStorage] = source.Spec.Resources.Requests[corev1.ResourceStorage]
+		} else if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	if restoreSize, _, _ := unstructured.NestedString(snap.Object, "status", "restoreSize"); restoreSize != "" {
+		size, err := resource.ParseQuantity(restoreSize)
+		if err != nil {
+			return nil, err
+		}
+		spec.Resources.Requests[corev1.ResourceStorage] = size
+	}
+	if _, exists := spec.Resources.Requests[corev1.ResourceStorage]; !exists {
+		return nil, fmt.Errorf("Can't tell what size to restore snapshot %s as", snap.GetName())
+	}
+	if s.StorageClassName != "" {
+		spec.StorageClassName = &s.StorageClassName
+	}
+	apiGroup := snapshotv1beta1.GroupName
+	spec.DataSource = &corev1.TypedLocalObjectReference{
+		APIGroup: &apiGroup,
+		Kind:     "VolumeSnapshot",
+		Name:     snap.GetName(),
+	}
+
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      names.NameGenerator.GenerateName(names.SimpleNameGenerator, bm.ObjectMeta.Name+"-restore-"),
+			Namespace: "default",
+			Labels: map[string]string{
+				"workloadname": actionName,
+			},
+		},
+		Spec: spec,
+	}, nil
+}
+
+// Clones every selected volume, returning the PVCs that were created
+func (r *BenchmarkReconciler) CloneVolume(bm *cnsbench.Benchmark, a cnsbench.ControlOperation) ([]client.Object, error) {
+	s := a.CloneSpec
+	if s.WorkloadName == "" && s.VolumeName == "" {
+		return nil, fmt.Errorf("Clone must set either workloadName or volumeName")
+	}
+	pvcs, err := r.selectPVCs(s.WorkloadName, s.VolumeName)
+	if err != nil {
+		return nil, err
+	}
+
+	var created []client.Object
+	for _, source := range pvcs.Items {
+		spec := corev1.PersistentVolumeClaimSpec{
+			AccessModes:      source.Spec.AccessModes,
+			StorageClassName: source.Spec.StorageClassName,
+			VolumeMode:       source.Spec.VolumeMode,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: source.Spec.Resources.Requests[corev1.ResourceStorage],
+				},
+			},
+			DataSource: &corev1.TypedLocalObjectReference{
+				Kind: "PersistentVolumeClaim",
+				Name: source.Name,
+			},
+		}
+		if s.StorageClassName != "" {
+			spec.StorageClassName = &s.StorageClassName
+		}
+		clone := corev1.PersistentVolumeClaim{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      name,
+				Name:      names.NameGenerator.GenerateName(names.SimpleNameGenerator, bm.ObjectMeta.Name+"-clone-"),
+				Namespace: "default",
+				Labels: map[string]string{
+					"workloadname": a.Name,
+				},
+			},
+			Spec: spec,
+		}
+
+		start := time.Now()
+		if err := r.createObj(bm, client.Object(&clone), true); err != nil {
+			r.Log.Error(err, "Creating clone", "source", source.Name)
+			continue
+		}
+		go r.trackBind(bm, a, "cloneVolume", clone.Name, start, "source", source.Name)
+		created = append(created, &clone)
+	}
+
+	return created, nil
+}
+
+// Waits for a new PVC to be bound and sends a metric of the given type with
+// how long it took
+func (r *BenchmarkReconciler) trackBind(bm *cnsbench.Benchmark, a cnsbench.ControlOperation, metricType, name string, start time.Time, metrics ...string) {
+	latency, err := waitForOp(start, func() (bool, error) {
+		pvc := &corev1.PersistentVolumeClaim{}
+		if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: name, Namespace: "default"}, pvc); errors.IsNotFound(err) {
+			// The PVC can take a moment to show up in the cache
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		return pvc.Status.Phase == corev1.ClaimBound, nil
+	})
+
+	metrics = append([]string{"name", name, "latencyMs", millis(latency)}, metrics...)
+	if err != nil {
+		r.Log.Error(err, "Waiting for volume to be bound", "name", name)
+		metrics = append(metrics, "error", err.Error())
+	}
+	r.opMetric(bm, a, metricType, metrics...)
+}
+
+// Starts short-lived pods that mount volumes, returning the pods.  Each pod is
+// deleted once it is running, and how long it took to mount and unmount the
+// volume is tracked in the background.
+func (r *BenchmarkReconciler) ChurnMounts(bm *cnsbench.Benchmark, a cnsbench.ControlOperation) ([]client.Object, error) {
+	m := a.MountChurnSpec
+	if m.WorkloadName == "" && m.VolumeName == "" {
+		return nil, fmt.Errorf("Mount churn must set either workloadName or volumeName")
+	}
+	pvcs, err := r.selectPVCs(m.WorkloadName, m.VolumeName)
+	if err != nil {
+		return nil, err
+	}
+	var bound []unstructured.Unstructured
+	for i := range pvcs.Items {
+		if pvcs.Items[i].Status.Phase != corev1.ClaimBound || pvcs.Items[i].DeletionTimestamp != nil {
+			continue
+		}
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&pvcs.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		bound = append(bound, unstructured.Unstructured{Object: u})
+	}
+	if len(bound) == 0 {
+		r.Log.Info("No bound volumes to mount")
+		return nil, nil
+	}
+
+	image := m.Image
+	if image == "" {
+		image = "busybox"
+	}
+
+	var pods []client.Object
+	for _, pvc := range pickObjs(bound, policyOr(m.Policy, "random"), m.Count, r.opRNG(bm, a.Name, m.Seed)) {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      names.NameGenerator.GenerateName(names.SimpleNameGenerator, bm.ObjectMeta.Name+"-mount-"),
 				Namespace: "default",
 				Labels: map[string]string{
-					"workloadname": actionName,
+					"workloadname": a.Name,
 				},
 			},
-			Spec: snapshotv1beta1.VolumeSnapshotSpec{
-				VolumeSnapshotClassName: &s.SnapshotClass,
-				Source: snapshotv1beta1.VolumeSnapshotSource{
-					PersistentVolumeClaimName: &volName,
+			Spec: corev1.PodSpec{
+				RestartPolicy: "Never",
+				// The pod is only there to mount the volume, don't
+				// make unmounting it wait on a shutdown
+				TerminationGracePeriodSeconds: utilptr.Int64Ptr(0),
+				Containers: []corev1.Container{
+					{
+						Name:    "mount-container",
+						Image:   image,
+						Command: []string{"sleep", "3600"},
+						VolumeMounts: []corev1.VolumeMount{
+							{
+								MountPath: "/data",
+								Name:      "data",
+							},
+						},
+					},
+				},
+				Volumes: []corev1.Volume{
+					{
+						Name: "data",
+						VolumeSource: corev1.VolumeSource{
+							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+								ClaimName: pvc.GetName(),
+							},
+						},
+					},
 				},
 			},
 		}
 
-		if err := r.createObj(bm, client.Object(&snap), false); err != nil {
-			r.Log.Error(err, "Creating snapshot")
+		start := time.Now()
+		if err := r.createObj(bm, client.Object(pod), true); err != nil {
+			r.Log.Error(err, "Creating mount pod", "pvc", pvc.GetName())
+			continue
 		}
+		go r.trackMount(bm, a, pod.Name, pvc.GetName(), start)
+
+		// Typed objects lose their kind once created, and the op
+		// isn't complete until the pod is gone again
+		gone := &unstructured.Unstructured{}
+		gone.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Pod"))
+		gone.SetName(pod.Name)
+		gone.SetNamespace(pod.Namespace)
+		pods = append(pods, gone)
 	}
 
-	return nil
+	return pods, nil
+}
+
+// Waits for a mount pod to run, deletes it, then waits for it to be gone
+func (r *BenchmarkReconciler) trackMount(bm *cnsbench.Benchmark, a cnsbench.ControlOperation, podName, pvcName string, start time.Time) {
+	key := client.ObjectKey{Name: podName, Namespace: "default"}
+	var node string
+	publishLatency, err := waitForOp(start, func() (bool, error) {
+		pod := &corev1.Pod{}
+		if err := r.Client.Get(context.TODO(), key, pod); errors.IsNotFound(err) {
+			// The pod can take a moment to show up in the cache
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		node = pod.Spec.NodeName
+		return pod.Status.Phase == corev1.PodRunning, nil
+	})
+	metrics := []string{"pod", podName, "pvc", pvcName, "node", node, "publishMs", millis(publishLatency)}
+
+	// Delete the pod even if it never ran, so it doesn't keep the volume
+	// attached
+	deleteStart := time.Now()
+	pod := &corev1.Pod{}
+	pod.Name, pod.Namespace = podName, "default"
+	if deleteErr := r.Client.Delete(context.TODO(), pod); deleteErr != nil && !errors.IsNotFound(deleteErr) && err == nil {
+		err = deleteErr
+	}
+	if err == nil {
+		var unpublishLatency time.Duration
+		unpublishLatency, err = waitForOp(deleteStart, func() (bool, error) {
+			err := r.Client.Get(context.TODO(), key, &corev1.Pod{})
+			if errors.IsNotFound(err) {
+				return true, nil
+			}
+			return false, err
+		})
+		metrics = append(metrics, "unpublishMs", millis(unpublishLatency), "latencyMs", millis(time.Since(start)))
+	}
+
+	if err != nil {
+		r.Log.Error(err, "Churning mount", "pod", podName)
+		metrics = append(metrics, "error", err.Error())
+	}
+	r.opMetric(bm, a, "mountChurn", metrics...)
+}
+
+// Creates an object from a library template, returning the object.  Namespaced
+// objects are created in the default namespace and owned by the Benchmark,
+// cluster-scoped objects are left for the user to clean up.
+func (r *BenchmarkReconciler) CreateFromTemplate(bm *cnsbench.Benchmark, a cnsbench.ControlOperation, rateCounter, firingNum int) ([]client.Object, error) {
+	c := a.CreateSpec
+	cm := &corev1.ConfigMap{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: c.ConfigMapName, Namespace: LIBRARY_NAMESPACE}, cm); err != nil {
+		r.Log.Error(err, "Error getting ConfigMap", "spec", c.ConfigMapName)
+		return nil, err
+	}
+	template, exists := cm.Data[c.Key]
+	if !exists {
+		return nil, fmt.Errorf("ConfigMap %s has no key %s", c.ConfigMapName, c.Key)
+	}
+
+	template = strings.ReplaceAll(template, "{{RATE_COUNTER}}", strconv.Itoa(rateCounter))
+	template = strings.ReplaceAll(template, "{{FIRING_NUM}}", strconv.Itoa(firingNum))
+	spec := cnsbench.Workload{Name: a.Name, Workload: c.ConfigMapName, Count: 1, Vars: c.Vars}
+	cmString := r.replaceVars(template, spec, 0, 1, a.Name, cm)
+
+	// The template can be any kind of object, including ones the scheme
+	// doesn't know about
+	obj := &unstructured.Unstructured{}
+	if err := k8syaml.NewYAMLOrJSONDecoder(strings.NewReader(cmString), 4096).Decode(&obj.Object); err != nil {
+		r.Log.Info("cm", "cm", cmString)
+		r.Log.Error(err, "Error decoding yaml")
+		return nil, err
+	}
+
+	gvk := obj.GroupVersionKind()
+	mapping, err := r.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+	makeOwner := false
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace("default")
+		}
+		// Ownership can't transcend namespaces
+		makeOwner = obj.GetNamespace() == "default"
+	}
+
+	objLabels := obj.GetLabels()
+	if objLabels == nil {
+		objLabels = make(map[string]string)
+	}
+	objLabels["workloadname"] = a.Name
+	obj.SetLabels(objLabels)
+
+	if err := r.createObj(bm, obj, makeOwner); err != nil {
+		return nil, err
+	}
+	return []client.Object{obj}, nil
+}
+
+// Returns the running pods of a workload, optionally only those with the given
+// role, along with the selector used to find them
+func (r *BenchmarkReconciler) runningPods(workloadName, role string) ([]unstructured.Unstructured, labels.Selector, error) {
+	ls := &metav1.LabelSelector{}
+	ls = metav1.AddLabelToSelector(ls, "workloadname", workloadName)
+	if role != "" {
+		ls = metav1.AddLabelToSelector(ls, "role", role)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(ls)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pods := &unstructured.UnstructuredList{}
+	pods.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("PodList"))
+	if err := r.Client.List(context.TODO(), pods, &client.ListOptions{Namespace: "default", LabelSelector: selector}); err != nil {
+		return nil, nil, err
+	}
+	var running []unstructured.Unstructured
+	for _, pod := range pods.Items {
+		phase, _, _ := unstructured.NestedString(pod.Object, "status", "phase")
+		if phase == string(corev1.PodRunning) && pod.GetDeletionTimestamp() == nil {
+			running = append(running, pod)
+		}
+	}
+	return running, selector, nil
+}
+
+// Kills running pods of a workload, returning the pods that were killed.  How
+// long the workload takes to recover is tracked in the background.
+func (r *BenchmarkReconciler) KillPods(bm *cnsbench.Benchmark, a cnsbench.ControlOperation) ([]client.Object, error) {
+	k := a.PodKillSpec
+	// Killing a pod that hasn't started yet tells us nothing about failover
+	running, selector, err := r.runningPods(k.WorkloadName, k.Role)
+	if err != nil {
+		return nil, err
+	}
+	if len(running) == 0 {
+		r.Log.Info("No running pods to kill", "workload", k.WorkloadName)
+		return nil, nil
+	}
+
+	var opts []client.DeleteOption
+	gracePeriod := "default"
+	if k.GracePeriodSeconds != nil {
+		opts = append(opts, client.GracePeriodSeconds(*k.GracePeriodSeconds))
+		gracePeriod = strconv.FormatInt(*k.GracePeriodSeconds, 10)
+	}
+
+	start := time.Now()
+	var killed []client.Object
+	var podNames []string
+	policy := policyOr(k.Policy, "random")
+	for _, pod := range pickObjs(running, policy, k.Count, r.opRNG(bm, a.Name, k.Seed)) {
+		pod := pod
+		r.Log.Info("Killing pod", "name", pod.GetName())
+		if err := r.Client.Delete(context.TODO(), &pod, opts...); err != nil && !errors.IsNotFound(err) {
+			return killed, err
+		}
+		killed = append(killed, &pod)
+		podNames = append(podNames, pod.GetName())
+	}
+
+	go r.trackRecovery(bm, a, selector, killed, len(running), start, "workload", k.WorkloadName, "pods", strings.Join(podNames, ","), "policy", policy, "gracePeriod", gracePeriod)
+	return killed, nil
 }
 
-func (r *BenchmarkReconciler) DeleteObj(bm *cnsbench.Benchmark, d cnsbench.Delete) error {
-	// TODO: Generalize to more than just snapshots.  I think we need to get all the api groups,
-	// then get all the kinds in those groups, then just iterate through those kinds searching
-	// for objects matching the spec
-	// See https://godoc.org/k8s.io/client-go/discovery
+// Waits for killed pods to be gone and for a workload to have as many ready
+// pods as it had before they were killed.  A pod can't be running before its
+// volumes have been attached and mounted, so this covers the volumes failing
+// over too.
+func (r *BenchmarkReconciler) trackRecovery(bm *cnsbench.Benchmark, a cnsbench.ControlOperation, selector labels.Selector, killed []client.Object, want int, start time.Time, metrics ...string) {
+	killedUIDs := make(map[types.UID]bool)
+	for _, pod := range killed {
+		killedUIDs[pod.GetUID()] = true
+	}
 
+	var goneLatency time.Duration
+	latency, err := waitForOp(start, func() (bool, error) {
+		pods := &corev1.PodList{}
+		if err := r.Client.List(context.TODO(), pods, &client.ListOptions{Namespace: "default", LabelSelector: selector}); err != nil {
+			return false, err
+		}
+		ready, gone := 0, true
+		for _, pod := range pods.Items {
+			if killedUIDs[pod.UID] {
+				gone = false
+			} else if pod.DeletionTimestamp == nil && pod.Status.Phase == corev1.PodRunning && podReady(pod) {
+				ready += 1
+			}
+		}
+		if gone && goneLatency == 0 {
+			goneLatency = time.Since(start)
+		}
+		return gone && ready >= want, nil
+	})
+
+	metrics = append(metrics, "goneMs", millis(goneLatency), "latencyMs", millis(latency))
+	if err != nil {
+		r.Log.Error(err, "Waiting for workload to recover")
+		metrics = append(metrics, "error", err.Error())
+	}
+	r.opMetric(bm, a, "podKill", metrics...)
+}
+
+func podReady(pod corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// Returns the objects that were deleted
+func (r *BenchmarkReconciler) DeleteObj(bm *cnsbench.Benchmark, a cnsbench.ControlOperation) ([]client.Object, error) {
+	d := a.DeleteSpec
 	r.Log.Info("Delete object")
 
 	labelSelector, err := metav1.LabelSelectorAsSelector(&d.Selector)
+	if err != nil {
+		return nil, err
+	}
+	// Deleting every object of a kind would also hit objects that have
+	// nothing to do with the benchmark
+	if labelSelector.Empty() {
+		return nil, fmt.Errorf("Delete %s must set a selector", a.Name)
+	}
+
+	var gvks []schema.GroupVersionKind
+	searching := d.APIVersion == "" || d.Kind == ""
+	if !searching {
+		gvks = append(gvks, schema.FromAPIVersionAndKind(d.APIVersion, d.Kind))
+	} else if gvks, err = r.deletableKinds(d.Kinds); err != nil {
+		return nil, err
+	}
+
+	// The same object can be served under more than one group, e.g. Events
+	var objs []unstructured.Unstructured
+	seen := make(map[types.UID]bool)
+	for _, gvk := range gvks {
+		objList := &unstructured.UnstructuredList{}
+		objList.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := r.Client.List(context.TODO(), objList, &client.ListOptions{Namespace: "default", LabelSelector: labelSelector}); err != nil {
+			// Discovery reports kinds the controller isn't allowed
+			// to list, which can't be ones it needs to delete
+			if searching && (errors.IsForbidden(err) || errors.IsMethodNotSupported(err) || errors.IsNotFound(err)) {
+				r.Log.Info("Skipping kind that can't be listed", "kind", gvk.String(), "reason", err.Error())
+				continue
+			}
+			return nil, err
+		}
+		for _, obj := range objList.Items {
+			if !seen[obj.GetUID()] && obj.GetUID() != bm.GetUID() {
+				seen[obj.GetUID()] = true
+				objs = append(objs, obj)
+			}
+		}
+	}
+	if len(objs) == 0 {
+		r.Log.Info("No objects found")
+		return nil, nil
+	}
+
+	var deleted []client.Object
+	for _, obj := range pickObjs(objs, d.Policy, d.Count, r.opRNG(bm, a.Name, d.Seed)) {
+		obj := obj
+		r.Log.Info("Deleting item", "name", obj.GetName(), "createtime", obj.GetCreationTimestamp().Unix())
+		if err := r.Client.Delete(context.TODO(), &obj); err != nil && !errors.IsNotFound(err) {
+			return deleted, err
+		}
+		age := time.Since(obj.GetCreationTimestamp().Time)
+		r.opMetric(bm, a, "deleteObj", "name", obj.GetName(), "kind", obj.GetKind(), "policy", d.Policy, "ageMs", millis(age))
+		deleted = append(deleted, &obj)
+	}
+
+	return deleted, nil
+}
+
+// Uses discovery to find every kind of namespaced object that can be listed
+// and deleted.  If kinds is not empty, only those kinds are returned.
+// Discovery results are cached, and the cache is refreshed if one of the kinds
+// isn't found in it, e.g. because its CRD was installed after the cache was
+// filled.
+func (r *BenchmarkReconciler) deletableKinds(kinds []string) ([]schema.GroupVersionKind, error) {
+	gvks, missing, err := r.findDeletableKinds(kinds)
+	if err != nil || !missing {
+		return gvks, err
+	}
+	r.discovery.Invalidate()
+	gvks, _, err = r.findDeletableKinds(kinds)
+	return gvks, err
+}
+
+// Returns the deletable kinds, and whether any of the wanted kinds were not
+// found
+func (r *BenchmarkReconciler) findDeletableKinds(kinds []string) ([]schema.GroupVersionKind, bool, error) {
+	resourceLists, err := r.discovery.ServerPreferredNamespacedResources()
+	if err != nil {
+		// Discovery fails if any one API group is unavailable (e.g. a
+		// broken metrics server), but still returns the rest
+		if len(resourceLists) == 0 {
+			return nil, false, err
+		}
+		r.Log.Error(err, "Some API groups could not be discovered")
+	}
+
+	wanted := make(map[string]bool)
+	for _, k := range kinds {
+		wanted[strings.ToLower(k)] = true
+	}
+	found := make(map[string]bool)
+
+	var gvks []schema.GroupVersionKind
+	for _, list := range resourceLists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, res := range list.APIResources {
+			// Skip subresources, e.g. pods/log
+			if strings.Contains(res.Name, "/") {
+				continue
+			}
+			verbs := sets.NewString(res.Verbs...)
+			if !verbs.HasAll("list", "delete") {
+				continue
+			}
+			if len(wanted) > 0 && !wanted[strings.ToLower(res.Kind)] {
+				continue
+			}
+			found[strings.ToLower(res.Kind)] = true
+			gvks = append(gvks, gv.WithKind(res.Kind))
+		}
+	}
+	return gvks, len(found) < len(wanted), nil
+}
+
+// Scales an object through its scale subresource.  The new replicas becoming
+// ready is tracked in the background.
+func (r *BenchmarkReconciler) ScaleNative(bm *cnsbench.Benchmark, a cnsbench.ControlOperation, numReplicas int) error {
+	s := a.ScaleSpec
+	if s.Kind == "" {
+		return fmt.Errorf("Scale of %s must set either kind or scaleScripts", s.ObjName)
+	}
+	if numReplicas < 0 {
+		numReplicas = 0
+	}
+	apiVersion := s.APIVersion
+	if apiVersion == "" {
+		apiVersion = "apps/v1"
+	}
+	gvk := schema.FromAPIVersionAndKind(apiVersion, s.Kind)
+	mapping, err := r.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return err
+	}
+
+	scales := r.scaleClient.Scales("default")
+	old, err := scales.Get(context.TODO(), mapping.Resource.GroupResource(), s.ObjName, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
-	objList := &unstructured.UnstructuredList{}
-	objList.SetAPIVersion(d.APIVersion)
-	objList.SetKind(d.Kind)
-	if err := r.Client.List(context.TODO(), objList, &client.ListOptions{Namespace: "default", LabelSelector: labelSelector}); err != nil {
+	if old.Spec.Replicas == int32(numReplicas) {
+		return nil
+	}
+
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, numReplicas))
+	start := time.Now()
+	if _, err := scales.Patch(context.TODO(), mapping.Resource, s.ObjName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
 		return err
 	}
-	sort.Slice(objList.Items, func(i, j int) bool {
-		return objList.Items[i].GetCreationTimestamp().Unix() < objList.Items[j].GetCreationTimestamp().Unix()
+	r.Log.Info("Scaling object", "name", s.ObjName, "kind", s.Kind, "from", old.Spec.Replicas, "to", numReplicas)
+	go r.trackScale(bm, a, gvk, mapping.Resource.GroupResource(), s.ObjName, old.Spec.Replicas, int32(numReplicas), start)
+	return nil
+}
+
+// Waits for a scaled object to have the requested number of ready replicas
+// and reports how long it took
+func (r *BenchmarkReconciler) trackScale(bm *cnsbench.Benchmark, a cnsbench.ControlOperation, gvk schema.GroupVersionKind, gr schema.GroupResource, name string, from, to int32, start time.Time) {
+	latency, err := waitForOp(start, func() (bool, error) {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: name, Namespace: "default"}, obj); err != nil {
+			return false, err
+		}
+		ready, found, _ := unstructured.NestedInt64(obj.Object, "status", "readyReplicas")
+		if !found && gvk.Group != "apps" {
+			// Not every custom resource reports ready replicas, fall
+			// back to what its scale subresource says
+			scale, err := r.scaleClient.Scales("default").Get(context.TODO(), gr, name, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			ready = int64(scale.Status.Replicas)
+		}
+		// The apps controllers leave readyReplicas out once it's 0
+		return ready == int64(to), nil
 	})
-	if len(objList.Items) > 0 {
-		r.Log.Info("Deleting first item", "name", objList.Items[0].GetName(), "createtime", objList.Items[0].GetCreationTimestamp().Unix())
-		return r.Client.Delete(context.TODO(), &objList.Items[0])
+
+	metrics := []string{"name", name, "kind", gvk.Kind, "from", strconv.Itoa(int(from)), "to", strconv.Itoa(int(to)), "latencyMs", millis(latency)}
+	if err != nil {
+		r.Log.Error(err, "Waiting for object to scale", "name", name)
+		metrics = append(metrics, "error", err.Error())
+	}
+	r.opMetric(bm, a, "scaleObj", metrics...)
+}
+
+// Scales every object CNSBench instantiated for a workload.  The kind of
+// object to scale is the one in the workload's library ConfigMap annotated
+// with scaleTarget, or failing that the one with the workload role.  If the
+// ConfigMap has a scaleScripts annotation, the objects are scaled with those
+// scripts, otherwise they are scaled directly.
+func (r *BenchmarkReconciler) ScaleWorkload(bm *cnsbench.Benchmark, a cnsbench.ControlOperation, numReplicas int) error {
+	s := a.ScaleSpec
+	cm, gvk, objs, err := r.workloadScaleTargets(bm, s.WorkloadName)
+	if err != nil {
+		return err
 	}
-	r.Log.Info("No objects found")
 
+	target := s
+	target.APIVersion, target.Kind = gvk.GroupVersion().String(), gvk.Kind
+	if target.ScaleScripts == "" {
+		target.ScaleScripts = cm.ObjectMeta.Annotations["scaleScripts"]
+	}
+	if target.ServiceAccountName == "" {
+		target.ServiceAccountName = cm.ObjectMeta.Annotations["scaleServiceAccount"]
+	}
+	for _, obj := range objs {
+		target.ObjName = obj.GetName()
+		a.ScaleSpec = target
+		if target.ScaleScripts != "" {
+			err = r.ScaleObj(bm, a, numReplicas)
+		} else {
+			err = r.ScaleNative(bm, a, numReplicas)
+		}
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (r *BenchmarkReconciler) ScaleObj(bm *cnsbench.Benchmark, s cnsbench.Scale, numReplicas int) error {
+// Returns the objects CNSBench instantiated for a workload that scaling the
+// workload should scale, along with their kind and the workload's library
+// ConfigMap
+func (r *BenchmarkReconciler) workloadScaleTargets(bm *cnsbench.Benchmark, workloadName string) (*corev1.ConfigMap, schema.GroupVersionKind, []unstructured.Unstructured, error) {
+	var workload *cnsbench.Workload
+	for i := range bm.Spec.Workloads {
+		if bm.Spec.Workloads[i].Name == workloadName {
+			workload = &bm.Spec.Workloads[i]
+			break
+		}
+	}
+	if workload == nil {
+		return nil, schema.GroupVersionKind{}, nil, fmt.Errorf("Scale of unknown workload %s", workloadName)
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: workload.Workload, Namespace: LIBRARY_NAMESPACE}, cm); err != nil {
+		r.Log.Error(err, "Error getting ConfigMap", "spec", workload.Workload)
+		return nil, schema.GroupVersionKind{}, nil, err
+	}
+	gvk, err := r.scaleTargetKind(cm, *workload)
+	if err != nil {
+		return nil, gvk, nil, err
+	}
+
+	ls := &metav1.LabelSelector{}
+	ls = metav1.AddLabelToSelector(ls, "workloadname", workloadName)
+	selector, err := metav1.LabelSelectorAsSelector(ls)
+	if err != nil {
+		return nil, gvk, nil, err
+	}
+	objs := &unstructured.UnstructuredList{}
+	objs.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := r.Client.List(context.TODO(), objs, &client.ListOptions{Namespace: "default", LabelSelector: selector}); err != nil {
+		return nil, gvk, nil, err
+	}
+	if len(objs.Items) == 0 {
+		return nil, gvk, nil, fmt.Errorf("No %s objects to scale for workload %s", gvk.Kind, workloadName)
+	}
+	return cm, gvk, objs.Items, nil
+}
+
+// Returns the kind of the object that should be scaled out of the objects in
+// a workload's library ConfigMap
+func (r *BenchmarkReconciler) scaleTargetKind(cm *corev1.ConfigMap, workload cnsbench.Workload) (schema.GroupVersionKind, error) {
+	var roleMatch *unstructured.Unstructured
+	for k, v := range cm.Data {
+		if strings.Contains(k, "parse") {
+			continue
+		}
+		// The objects may be custom resources the scheme doesn't know
+		// about, so decode them without it
+		obj := &unstructured.Unstructured{}
+		cmString := r.replaceVars(v, workload, 0, workload.Count, workload.Name, cm)
+		if err := k8syaml.NewYAMLOrJSONDecoder(strings.NewReader(cmString), 4096).Decode(&obj.Object); err != nil {
+			r.Log.Error(err, "Error decoding yaml", "key", k)
+			continue
+		}
+		annotations := obj.GetAnnotations()
+		if _, exists := annotations["scaleTarget"]; exists {
+			return obj.GroupVersionKind(), nil
+		}
+		if r.getRole(annotations) == "workload" && roleMatch == nil {
+			roleMatch = obj
+		}
+	}
+	if roleMatch == nil {
+		return schema.GroupVersionKind{}, fmt.Errorf("Workload %s has no object to scale", cm.Name)
+	}
+	return roleMatch.GroupVersionKind(), nil
+}
+
+// Scales an object by running a script from the library in a pod
+func (r *BenchmarkReconciler) ScaleObj(bm *cnsbench.Benchmark, a cnsbench.ControlOperation, numReplicas int) error {
+	s := a.ScaleSpec
 	// For now, the way this works is: a configmap in the library namespace
 	// contains scripts for scaling up/down an object.  In a Scale control
 	// op spec, the user specifies the name of the object they want to
@@ -226,20 +1187,9 @@ func (r *BenchmarkReconciler) ScaleObj(bm *cnsbench.Benchmark, s cnsbench.Scale,
 	// configmap in to the default namespace, create a pod that attaches
 	// that configmap, and run the scale script in that pod.
 	//
-	// Once we are able to include more complicated objects (i.e.,
-	// non-default resource types) in a workload, then we will add a field
-	// to the Scale spec that references a Workload in the Benchmark (like
-	// the WorkloadName field of a Snapshot control op spec).  That will
-	// let us lookup the target object (so the user doesn't need to specify
-	// the actual name of the object to be scaled), and the workload spec
-	// should include the scale scripts so we can look those up as well.
-	//
-	// Summary: Currently, a user must supply both the name of the object
-	// to be scaled and the name of a configmap in the library namespace
-	// that has scripts for scaling that object.  In the future, they will
-	// be able to instead just supply the name of a workload that CNSBench
-	// has already instantiated, and CNSBench will be able to lookup both
-	// the scale scripts and the target object from that alone.
+	// Users can also just supply the name of a workload that CNSBench has
+	// already instantiated, in which case ScaleWorkload looks up both the
+	// scale scripts and the target object and then calls this.
 
 	// TODO: Check to see if a copy of the scale script configmap already
 	// exists, use that if so.
@@ -299,14 +1249,68 @@ func (r *BenchmarkReconciler) ScaleObj(bm *cnsbench.Benchmark, s cnsbench.Scale,
 	return err
 }
 
+// Starts new instances of a workload, or stops its newest instances, until it
+// has target instances running
+func (r *BenchmarkReconciler) SetWorkloadConcurrency(bm *cnsbench.Benchmark, a cnsbench.Workload, target int) error {
+	r.instanceMutex.Lock()
+	defer r.instanceMutex.Unlock()
+	return r.setWorkloadConcurrency(bm, a, target)
+}
+
+// Must be called with instanceMutex held
+func (r *BenchmarkReconciler) setWorkloadConcurrency(bm *cnsbench.Benchmark, a cnsbench.Workload, target int) error {
+	if target < 0 {
+		target = 0
+	}
+	live, err := LiveWorkloadInstances(r.apiReader, a.Name)
+	if err != nil {
+		return err
+	}
+	if len(live) == target {
+		return nil
+	}
+	r.Log.Info("Setting workload concurrency", "workload", a.Name, "live", len(live), "target", target)
+	r.metric(bm, "workloadConcurrency", "workloadName", a.Name, "live", strconv.Itoa(len(live)), "target", strconv.Itoa(target))
+
+	if len(live) < target {
+		more := a
+		more.Count = target - len(live)
+		return r.runWorkload(bm, more, a.Name)
+	}
+
+	background := metav1.DeletePropagationBackground
+	for _, obj := range live[:len(live)-target] {
+		r.Log.Info("Stopping workload instance", "name", obj.GetName())
+		if err := r.Client.Delete(context.TODO(), obj, &client.DeleteOptions{PropagationPolicy: &background}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *BenchmarkReconciler) ReconcileInstances(bm *cnsbench.Benchmark, workloads []cnsbench.Workload) error {
 	var err error
 	cm := &corev1.ConfigMap{}
 	accessor := meta.NewAccessor()
 
+	// Rates may be starting or stopping instances at the same time
+	r.instanceMutex.Lock()
+	defer r.instanceMutex.Unlock()
+
 	for _, a := range workloads {
 		fmt.Println(a)
 
+		// Workloads whose concurrency is set by a rate are kept at the
+		// rate's most recent value instead of at count
+		if a.RateMode == "target" {
+			if target, exists := r.workloadTarget(bm, a.Name); exists {
+				if err := r.setWorkloadConcurrency(bm, a, target); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
 		// Check how many workloads are complete and how many exist (running or otherwise) but aren't complete
 		workloadsNeeded := 0
 		var workloadsComplete, workloadsNotComplete int
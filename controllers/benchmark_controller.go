@@ -19,7 +19,11 @@ package controllers
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -35,7 +39,16 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/scale"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 )
 
@@ -48,8 +61,32 @@ type BenchmarkReconciler struct {
 	Scheme           *runtime.Scheme
 	controlChannels  map[string](chan bool)
 	controller       controller.Controller
+	discovery        discovery.CachedDiscoveryInterface
+	restMapper       meta.RESTMapper
+	scaleClient      scale.ScalesGetter
+	clientset        kubernetes.Interface
+	restConfig       *rest.Config
 	ScriptsDir       string
 	workloadInstance map[string]int
+	// Held while starting or stopping workload instances, so that two
+	// goroutines can't both count too few instances and start more, and
+	// while using workloadInstance
+	instanceMutex sync.Mutex
+	// Reads straight from the API server.  Used to count workload
+	// instances, since the cache may not have seen instances that were
+	// just started or stopped.
+	apiReader  client.Reader
+	eventRates map[string][]*eventRate
+	eventMutex sync.Mutex
+	rateStats  map[string]map[string]*rates.Stats
+	// Number of instances each rate-driven workload should have, for
+	// workloads whose rateMode is "target"
+	workloadTargets map[string]map[string]int
+	targetMutex     sync.Mutex
+	// Random number generators used by control operations, so that a
+	// seeded operation makes the same choices every time it runs
+	opRNGs   map[string]map[string]*rand.Rand
+	rngMutex sync.Mutex
 }
 
 // +kubebuilder:rbac:groups=cnsbench.example.com,resources=benchmarks,verbs=get;list;watch;create;update;patch;delete
@@ -61,16 +98,40 @@ type BenchmarkReconciler struct {
 // +kubebuilder:rbac:groups=batch,resources=jobs,verbs=create;delete;get;list;patch;update;watch
 // +kubebuilder:rbac:groups=core,resources=services/finalizers;services;pods;endpoints;persistentvolumeclaims;events;configmaps;secrets,verbs=create;delete;get;list;patch;update;watch
 // +kubebuilder:rbac:groups=apps,resources=deployments;daemonsets;replicasets;statefulsets,verbs=create;delete;get;list;patch;update;watch
+// +kubebuilder:rbac:groups=*,resources=*/scale,verbs=get;patch;update
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;patch;update
+// +kubebuilder:rbac:groups=core,resources=pods/eviction,verbs=create
+// +kubebuilder:rbac:groups=core,resources=pods/exec,verbs=create
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=volumeattachments,verbs=get;list;watch
 
 func (r *BenchmarkReconciler) metric(instance *cnsbench.Benchmark, metricType string, metrics ...string) {
 	metrics = append([]string{"type", metricType}, metrics...)
 	output.Metric(instance.Spec.Outputs, instance.Spec.MetricsOutput, instance.ObjectMeta.Name, metrics...)
 }
 
+// Sends a metric to a control operation's output, or to the Benchmark's
+// metrics output if the operation doesn't name one
+func (r *BenchmarkReconciler) opMetric(instance *cnsbench.Benchmark, a cnsbench.ControlOperation, metricType string, metrics ...string) {
+	outputName := a.Outputs.OutputName
+	if outputName == "" {
+		outputName = instance.Spec.MetricsOutput
+	}
+	metrics = append([]string{"type", metricType, "controlOperation", a.Name}, metrics...)
+	output.Metric(instance.Spec.Outputs, outputName, instance.ObjectMeta.Name, metrics...)
+}
+
 func (r *BenchmarkReconciler) cleanup(instance *cnsbench.Benchmark) error {
 	r.Log.Info("Deleting", "finalizers", instance.GetFinalizers())
 	r.Log.Info("status", "status", instance.Status)
 	r.stopRoutines(instance)
+	r.deleteEventRates(instance)
+	delete(r.rateStats, instance.ObjectMeta.Name)
+	r.targetMutex.Lock()
+	delete(r.workloadTargets, instance.ObjectMeta.Name)
+	r.targetMutex.Unlock()
+	r.rngMutex.Lock()
+	delete(r.opRNGs, instance.ObjectMeta.Name)
+	r.rngMutex.Unlock()
 	if utils.Contains(instance.GetFinalizers(), "RateFinalizer") {
 		instance.SetFinalizers(utils.Remove(instance.GetFinalizers(), "RateFinalizer"))
 		if err := r.Client.Update(context.TODO(), instance); err != nil {
@@ -99,21 +160,73 @@ func (r *BenchmarkReconciler) startWorkloads(instance *cnsbench.Benchmark, workl
 	return nil
 }
 
+// Checks that every control operation that names an output names one of the
+// Benchmark's outputs, since metrics sent to an unknown output are dropped
+func checkOpOutputs(instance *cnsbench.Benchmark) error {
+	names := make(map[string]bool)
+	for _, o := range instance.Spec.Outputs {
+		names[o.Name] = true
+	}
+	for _, a := range instance.Spec.ControlOperations {
+		if a.Outputs.OutputName != "" && !names[a.Outputs.OutputName] {
+			return fmt.Errorf("Control operation %s uses unknown output %s", a.Name, a.Outputs.OutputName)
+		}
+	}
+	return nil
+}
+
 func (r *BenchmarkReconciler) startRates(instance *cnsbench.Benchmark) error {
 	instance.Status.RunningRates = 0
-	var c chan int
+	if err := checkOpOutputs(instance); err != nil {
+		r.Log.Error(err, "Checking control operation outputs")
+		return err
+	}
+	var err error
 	r.controlChannels[instance.ObjectMeta.Name] = make(chan bool)
-	for _, rate := range instance.Spec.Rates {
-		if rate.ConstantRateSpec.Interval != 0 {
-			c = r.createConstantRate(rate.ConstantRateSpec, r.controlChannels[instance.ObjectMeta.Name])
-		} else if rate.ConstantIncreaseDecreaseRateSpec.IncInterval != 0 {
-			c = r.createConstantIncreaseDecreaseRate(rate.ConstantIncreaseDecreaseRateSpec, r.controlChannels[instance.ObjectMeta.Name])
+	r.rateStats[instance.ObjectMeta.Name] = make(map[string]*rates.Stats)
+	for _, spec := range instance.Spec.Rates {
+		rate := rates.Rate{
+			Consumer:       make(chan int),
+			ControlChannel: r.controlChannels[instance.ObjectMeta.Name],
+			Metric:         r.rateMetric(instance, spec.Name),
+			Stats:          &rates.Stats{},
+		}
+		if err := setRateBounds(&rate, spec); err != nil {
+			r.Log.Error(err, "Error parsing rate bounds", "rate", spec.Name)
+			return err
+		}
+		r.rateStats[instance.ObjectMeta.Name][spec.Name] = rate.Stats
+
+		// Only closed-loop rates need to hear when their control
+		// operations have finished
+		var done chan int
+		if intervalSet(spec.ConstantRateSpec.Interval) {
+			err = r.createConstantRate(rate, spec.ConstantRateSpec)
+		} else if intervalSet(spec.ConstantIncreaseDecreaseRateSpec.IncInterval) {
+			err = r.createConstantIncreaseDecreaseRate(rate, spec.ConstantIncreaseDecreaseRateSpec)
+		} else if intervalSet(spec.PoissonRateSpec.Interval) {
+			err = r.createPoissonRate(rate, spec.PoissonRateSpec)
+		} else if spec.TraceRateSpec.ConfigMapName != "" {
+			err = r.createTraceRate(rate, spec.TraceRateSpec)
+		} else if spec.ScheduleRateSpec != nil {
+			err = r.createScheduleRate(rate, *spec.ScheduleRateSpec)
+		} else if spec.CronRateSpec != nil {
+			err = r.createCronRate(rate, *spec.CronRateSpec)
+		} else if spec.EventRateSpec != (cnsbench.EventRate{}) {
+			err = r.createEventRate(instance, rate, spec.EventRateSpec)
+		} else if spec.ClosedLoopRateSpec != nil {
+			done = make(chan int, spec.ClosedLoopRateSpec.Concurrency)
+			err = r.createClosedLoopRate(rate, *spec.ClosedLoopRateSpec, done)
 		} else {
 			unknownRate := errors.New("Unknown rate")
-			r.Log.Error(unknownRate, rate.Name)
+			r.Log.Error(unknownRate, spec.Name)
 			return unknownRate
 		}
-		go r.runControlOps(instance, c, r.controlChannels[instance.ObjectMeta.Name], rate.Name)
+		if err != nil {
+			r.Log.Error(err, "Error creating rate", "rate", spec.Name)
+			return err
+		}
+		go r.runControlOps(instance, rate.Consumer, done, r.controlChannels[instance.ObjectMeta.Name], spec.Name)
 		instance.Status.RunningRates += 1
 	}
 
@@ -126,6 +239,83 @@ func (r *BenchmarkReconciler) startRates(instance *cnsbench.Benchmark) error {
 	return nil
 }
 
+func (r *BenchmarkReconciler) setWorkloadTarget(bm *cnsbench.Benchmark, workloadName string, target int) {
+	r.targetMutex.Lock()
+	defer r.targetMutex.Unlock()
+	if _, exists := r.workloadTargets[bm.ObjectMeta.Name]; !exists {
+		r.workloadTargets[bm.ObjectMeta.Name] = make(map[string]int)
+	}
+	r.workloadTargets[bm.ObjectMeta.Name][workloadName] = target
+}
+
+// Returns the random number generator for a control operation, creating it
+// from seed the first time the operation asks for it.  A seed of 0 means
+// seed from the current time.
+func (r *BenchmarkReconciler) opRNG(bm *cnsbench.Benchmark, opName string, seed int64) *rand.Rand {
+	r.rngMutex.Lock()
+	defer r.rngMutex.Unlock()
+	if _, exists := r.opRNGs[bm.ObjectMeta.Name]; !exists {
+		r.opRNGs[bm.ObjectMeta.Name] = make(map[string]*rand.Rand)
+	}
+	rng, exists := r.opRNGs[bm.ObjectMeta.Name][opName]
+	if !exists {
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		rng = rand.New(rand.NewSource(seed))
+		r.opRNGs[bm.ObjectMeta.Name][opName] = rng
+	}
+	return rng
+}
+
+// A workload whose instance count is set by a rate is complete once the rate
+// has stopped firing for good and none of the workload's instances are still
+// running
+func (r *BenchmarkReconciler) targetWorkloadComplete(bm *cnsbench.Benchmark, w cnsbench.Workload) (bool, error) {
+	stats, exists := r.rateStats[bm.ObjectMeta.Name][w.RateName]
+	if !exists || !stats.Done() {
+		return false, nil
+	}
+	live, err := LiveWorkloadInstances(r.apiReader, w.Name)
+	if err != nil {
+		return false, err
+	}
+	return len(live) == 0, nil
+}
+
+// Returns false if the workload's rate hasn't fired yet
+func (r *BenchmarkReconciler) workloadTarget(bm *cnsbench.Benchmark, workloadName string) (int, bool) {
+	r.targetMutex.Lock()
+	defer r.targetMutex.Unlock()
+	target, exists := r.workloadTargets[bm.ObjectMeta.Name][workloadName]
+	return target, exists
+}
+
+// Copies the number of times each rate has fired into the instance's status.
+// Returns true if the status changed
+func (r *BenchmarkReconciler) syncRateStatus(instance *cnsbench.Benchmark) bool {
+	stats, exists := r.rateStats[instance.ObjectMeta.Name]
+	if !exists {
+		return false
+	}
+	statuses := make([]cnsbench.RateStatus, 0, len(instance.Spec.Rates))
+	for _, rate := range instance.Spec.Rates {
+		if s, exists := stats[rate.Name]; exists {
+			statuses = append(statuses, cnsbench.RateStatus{
+				Name:    rate.Name,
+				Firings: s.Firings(),
+				Dropped: s.Dropped(),
+				Delayed: s.Delayed(),
+			})
+		}
+	}
+	if reflect.DeepEqual(statuses, instance.Status.Rates) {
+		return false
+	}
+	instance.Status.Rates = statuses
+	return true
+}
+
 func (r *BenchmarkReconciler) doOutputs(bm *cnsbench.Benchmark, startTime, completionTime, initCompletionTime int64) {
 	r.Log.Info("Do outputs")
 
@@ -267,6 +457,19 @@ func (r *BenchmarkReconciler) Reconcile(ctx context.Context, request ctrl.Reques
 	} else if instance.Status.State == cnsbench.Running {
 		// if we're here, then we're either still running or haven't started yet
 
+		// Something we're watching changed, see if any event rates need to fire
+		if err := r.checkEvents(instance); err != nil {
+			r.Log.Error(err, "Error checking events")
+		}
+
+		// Not being able to record the firing counts isn't fatal, we'll get
+		// another chance next time we're reconciled
+		if r.syncRateStatus(instance) {
+			if err := r.Client.Status().Update(context.TODO(), instance); err != nil {
+				r.Log.Error(err, "Updating rate status")
+			}
+		}
+
 		// If we're running, and there's a runtime set, check if we've reached the runtime
 		// And if not, check that we still have the correct number of workload instances running.
 		runtimeEnd := time.Now()
@@ -279,6 +482,17 @@ func (r *BenchmarkReconciler) Reconcile(ctx context.Context, request ctrl.Reques
 			r.Log.Info("Checking status...")
 			complete := true
 			for _, w := range instance.Spec.Workloads {
+				if w.RateMode == "target" {
+					// Instances stopped when the target went down
+					// never complete, so count doesn't apply
+					if complete, err = r.targetWorkloadComplete(instance, w); err != nil {
+						r.Log.Error(err, "Error checking workload instances")
+						return ctrl.Result{}, err
+					} else if !complete {
+						break
+					}
+					continue
+				}
 				workloadsComplete, _, err := CountCompletions(r.Client, w.Name)
 				if err != nil {
 					r.Log.Error(err, "Error checking Job status")
@@ -297,6 +511,7 @@ func (r *BenchmarkReconciler) Reconcile(ctx context.Context, request ctrl.Reques
 		// Either runtime is set and we've reached it, or it's not set but all workloads are complete:
 		r.Log.Info("Pods are complete, doing outputs")
 		instance.Status.NumCompletedObjs, _ = r.getCompletedPods(instance.Spec.Workloads, runtimeEnd)
+		r.syncRateStatus(instance)
 		r.doOutputs(instance, instance.ObjectMeta.CreationTimestamp.Unix(), time.Now().Unix(), instance.Status.InitCompletionTimeUnix)
 
 		instance.Status.State = cnsbench.Complete
@@ -384,28 +599,309 @@ func (r *BenchmarkReconciler) Reconcile(ctx context.Context, request ctrl.Reques
 	return ctrl.Result{}, nil
 }
 
-// This will create the rate and run it in a separate goroutine.  Returns the
-// channel that the rate will trigger on
-func (r *BenchmarkReconciler) createConstantRate(spec cnsbench.ConstantRate, c chan bool) chan int {
-	r.Log.Info("Launching SingleRate")
-	consumerChan := make(chan int)
-	rate := rates.Rate{Consumer: consumerChan, ControlChannel: c}
-	go rate.SingleRate(rates.ConstTimer{Interval: spec.Interval})
-	return consumerChan
+// Fills in the bounds on when and how often the rate fires
+func setRateBounds(rate *rates.Rate, spec cnsbench.Rate) error {
+	var err error
+	if spec.StartAfter != "" {
+		if rate.StartAfter, err = time.ParseDuration(spec.StartAfter); err != nil {
+			return err
+		}
+	}
+	if spec.StopAfter != "" {
+		if rate.StopAfter, err = time.ParseDuration(spec.StopAfter); err != nil {
+			return err
+		}
+	}
+	rate.MaxFirings = spec.MaxFirings
+	if spec.Backpressure != nil {
+		rate.Policy = rates.Policy(spec.Backpressure.Policy)
+		rate.QueueLength = spec.Backpressure.QueueLength
+	}
+	return nil
+}
+
+func intervalSet(interval intstr.IntOrString) bool {
+	return interval.IntValue() != 0 || interval.StrVal != ""
+}
+
+// Rate intervals are either an integer number of seconds or a duration string
+func parseInterval(interval intstr.IntOrString) (time.Duration, error) {
+	var d time.Duration
+	if interval.Type == intstr.Int {
+		d = time.Duration(interval.IntVal) * time.Second
+	} else if secs, err := strconv.Atoi(interval.StrVal); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if d, err = time.ParseDuration(interval.StrVal); err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, errors.New("Interval must be greater than 0: " + interval.String())
+	}
+	return d, nil
+}
+
+// Returns a function that rates can use to send metrics tagged with the
+// rate's name
+func (r *BenchmarkReconciler) rateMetric(bm *cnsbench.Benchmark, rateName string) func(string, ...string) {
+	return func(metricType string, metrics ...string) {
+		r.metric(bm, metricType, append([]string{"rateName", rateName}, metrics...)...)
+	}
+}
+
+// This will run the rate in a separate goroutine.  The rate triggers on its
+// Consumer channel
+func (r *BenchmarkReconciler) createConstantRate(rate rates.Rate, spec cnsbench.ConstantRate) error {
+	interval, err := parseInterval(spec.Interval)
+	if err != nil {
+		return err
+	}
+	r.Log.Info("Launching SingleRate", "interval", interval)
+	go rate.SingleRate(rates.ConstTimer{Interval: interval})
+	return nil
+}
+
+// This will run the rate in a separate goroutine.  The rate triggers on its
+// Consumer channel
+func (r *BenchmarkReconciler) createConstantIncreaseDecreaseRate(rate rates.Rate, spec cnsbench.ConstantIncreaseDecreaseRate) error {
+	incInterval, err := parseInterval(spec.IncInterval)
+	if err != nil {
+		return err
+	}
+	decInterval, err := parseInterval(spec.DecInterval)
+	if err != nil {
+		return err
+	}
+	r.Log.Info("Launching IncDecRate", "incInterval", incInterval, "decInterval", decInterval)
+	go rate.IncDecRate(rates.ConstTimer{Interval: incInterval}, rates.ConstTimer{Interval: decInterval}, spec.Min, spec.Max)
+	return nil
 }
 
-// This will create the rate and run it in a separate goroutine.  Returns the
-// channel that the rate will trigger on
-func (r *BenchmarkReconciler) createConstantIncreaseDecreaseRate(spec cnsbench.ConstantIncreaseDecreaseRate, c chan bool) chan int {
-	r.Log.Info("Launching IncDecRate")
-	consumerChan := make(chan int)
-	rate := rates.Rate{Consumer: consumerChan, ControlChannel: c}
-	go rate.IncDecRate(rates.ConstTimer{Interval: spec.IncInterval}, rates.ConstTimer{Interval: spec.DecInterval}, spec.Min, spec.Max)
-	return consumerChan
+// This will run the rate in a separate goroutine.  The rate triggers on its
+// Consumer channel
+func (r *BenchmarkReconciler) createPoissonRate(rate rates.Rate, spec cnsbench.PoissonRate) error {
+	interval, err := parseInterval(spec.Interval)
+	if err != nil {
+		return err
+	}
+	r.Log.Info("Launching PoissonRate", "interval", interval)
+	go rate.SingleRate(rates.PoissonTimer{Interval: interval, Seed: spec.Seed})
+	return nil
+}
+
+// Loads the trace from the library namespace, then runs the rate in a separate
+// goroutine.  The rate triggers on its Consumer channel
+func (r *BenchmarkReconciler) createTraceRate(rate rates.Rate, spec cnsbench.TraceRate) error {
+	cm := &corev1.ConfigMap{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: spec.ConfigMapName, Namespace: LIBRARY_NAMESPACE}, cm); err != nil {
+		r.Log.Error(err, "Error getting ConfigMap", "name", spec.ConfigMapName)
+		return err
+	}
+
+	key := spec.Key
+	if key == "" {
+		if len(cm.Data) != 1 {
+			return errors.New("Trace ConfigMap " + spec.ConfigMapName + " does not have exactly one key, must specify one")
+		}
+		for k := range cm.Data {
+			key = k
+		}
+	}
+	data, exists := cm.Data[key]
+	if !exists {
+		return errors.New("Key " + key + " not found in trace ConfigMap " + spec.ConfigMapName)
+	}
+	offsets, err := rates.ParseTrace(data, spec.Format)
+	if err != nil {
+		return err
+	}
+
+	scale := 1.0
+	if spec.TimeScale != "" {
+		if scale, err = strconv.ParseFloat(spec.TimeScale, 64); err != nil {
+			return err
+		} else if scale <= 0 {
+			return errors.New("Trace timeScale must be greater than 0")
+		}
+	}
+
+	var period time.Duration
+	if spec.LoopPeriod != "" {
+		if period, err = time.ParseDuration(spec.LoopPeriod); err != nil {
+			return err
+		} else if len(offsets) > 0 && period < offsets[len(offsets)-1] {
+			return errors.New("Trace loopPeriod must be at least as long as the trace")
+		}
+	}
+
+	r.Log.Info("Launching TraceRate", "firings", len(offsets), "scale", scale, "loop", spec.Loop, "period", period)
+	go rate.SingleRate(rates.TraceTimer{Offsets: offsets, Scale: scale, Loop: spec.Loop, Period: period})
+	return nil
+}
+
+// This will run the rate in a separate goroutine.  The rate triggers on its
+// Consumer channel
+func (r *BenchmarkReconciler) createScheduleRate(rate rates.Rate, spec cnsbench.ScheduleRate) error {
+	stages := make([]rates.Stage, 0, len(spec.Stages))
+	for _, s := range spec.Stages {
+		stage := rates.Stage{Concurrency: s.Concurrency}
+		var err error
+		if stage.Duration, err = time.ParseDuration(s.Duration); err != nil {
+			return err
+		}
+		if intervalSet(s.Interval) {
+			if s.Concurrency != 0 {
+				return errors.New("Schedule stage can't have both an interval and a concurrency")
+			}
+			if stage.Interval, err = parseInterval(s.Interval); err != nil {
+				return err
+			}
+		}
+		stages = append(stages, stage)
+	}
+	r.Log.Info("Launching ScheduleRate", "stages", len(stages))
+	go rate.ScheduleRate(stages)
+	return nil
+}
+
+func (r *BenchmarkReconciler) createCronRate(rate rates.Rate, spec cnsbench.CronRate) error {
+	schedules := make([]rates.CronSchedule, 0, len(spec.Entries))
+	for _, e := range spec.Entries {
+		s, err := rates.ParseCron(e.Schedule)
+		if err != nil {
+			return err
+		}
+		schedules = append(schedules, s)
+	}
+	r.Log.Info("Launching CronRate", "entries", spec.Entries)
+	go rate.SingleRate(rates.CronTimer{Schedules: schedules})
+	return nil
+}
+
+func (r *BenchmarkReconciler) createClosedLoopRate(rate rates.Rate, spec cnsbench.ClosedLoopRate, done chan int) error {
+	var thinkTime time.Duration
+	if spec.ThinkTime != "" {
+		var err error
+		if thinkTime, err = time.ParseDuration(spec.ThinkTime); err != nil {
+			return err
+		}
+	}
+	r.Log.Info("Launching ClosedLoopRate", "concurrency", spec.Concurrency, "thinkTime", thinkTime)
+	go rate.ClosedLoopRate(spec.Concurrency, thinkTime, done)
+	return nil
+}
+
+// Shortest time between a closed-loop rate firing and it being told it can
+// fire again.  Firings that have nothing to wait for (e.g. workloads, exec, or
+// operations that failed) would otherwise complete immediately and make the
+// rate spin.
+const CLOSED_LOOP_MIN_INTERVAL = time.Second
+
+// Waits for the objects created or deleted by one firing of a closed-loop rate
+// to be ready or gone, then tells the rate it can fire again.  If they aren't
+// within OP_TIMEOUT (e.g. a PVC that will never be bound) the rate is told to
+// fire again anyway, so stuck operations don't stop the loop.  Either way, the
+// rate isn't told until CLOSED_LOOP_MIN_INTERVAL after the firing.
+func (r *BenchmarkReconciler) awaitOps(objs opObjects, n int, done chan<- int, stop <-chan struct{}) {
+	start := time.Now()
+	if len(objs.created) == 0 && len(objs.deleted) == 0 {
+		r.Log.Info("Firing created or deleted nothing to wait for", "n", n)
+	}
+	_, err := waitForOp(start, func() (bool, error) {
+		select {
+		case <-stop:
+			return false, errors.New("Rate stopped")
+		default:
+		}
+		complete, err := ObjsComplete(r.Client, objs.created, objs.deleted)
+		if err != nil {
+			r.Log.Error(err, "Checking whether control operations are complete")
+			return false, nil
+		}
+		return complete, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		r.Log.Info("Control operations did not complete in time, firing again", "n", n)
+	} else if err != nil {
+		// Stopped before the operations completed
+		return
+	}
+	select {
+	case <-time.After(CLOSED_LOOP_MIN_INTERVAL - time.Since(start)):
+	case <-stop:
+		return
+	}
+	select {
+	case done <- n:
+	case <-stop:
+	}
+}
+
+// Waits for the objects a control operation created or deleted to reach their
+// final state, then sends a controlOpComplete metric with how long the
+// operation took and fires any event rates waiting for the operation.
+// Operations that don't create or delete objects are complete as soon as they
+// return.
+func (r *BenchmarkReconciler) trackControlOp(bm *cnsbench.Benchmark, a cnsbench.ControlOperation, rateName string, objs opObjects, start time.Time, err error) {
+	if err == nil {
+		var lastErr error
+		_, err = waitForOp(start, func() (bool, error) {
+			complete, err := ObjsComplete(r.Client, objs.created, objs.deleted)
+			if err != nil {
+				// Objects can take a moment to show up in the
+				// cache, so keep trying
+				lastErr = err
+				return false, nil
+			}
+			return complete, nil
+		})
+		if err != nil && lastErr != nil {
+			err = fmt.Errorf("%v: %v", err, lastErr)
+		}
+	}
+	end := time.Now()
+
+	metrics := []string{
+		"rateName", rateName,
+		"objects", strconv.Itoa(len(objs.created) + len(objs.deleted)),
+		"start", strconv.FormatInt(start.UnixNano()/int64(time.Millisecond), 10),
+		"end", strconv.FormatInt(end.UnixNano()/int64(time.Millisecond), 10),
+		"durationMs", millis(end.Sub(start)),
+		"success", strconv.FormatBool(err == nil),
+	}
+	if err != nil {
+		r.Log.Error(err, "Control operation did not complete", "name", a.Name)
+		metrics = append(metrics, "error", err.Error())
+	}
+	r.opMetric(bm, a, "controlOpComplete", metrics...)
+
+	if err == nil {
+		r.controlOpDone(bm, a.Name)
+	}
+}
+
+// Extra rateFired metric fields describing why the rate fired.  Cron rates
+// fire with the index of the schedule entry that matched.
+func firingTags(bm *cnsbench.Benchmark, rateName string, n int) []string {
+	for _, rate := range bm.Spec.Rates {
+		if rate.Name != rateName || rate.CronRateSpec == nil || n < 1 || n > len(rate.CronRateSpec.Entries) {
+			continue
+		}
+		entry := rate.CronRateSpec.Entries[n-1]
+		if entry.Name == "" {
+			return []string{"entry", entry.Schedule}
+		}
+		return []string{"entry", entry.Name}
+	}
+	return nil
 }
 
 // This is triggered by a rate via the rateCh channel
-func (r *BenchmarkReconciler) runControlOps(bm *cnsbench.Benchmark, rateCh chan int, controlCh chan bool, rateName string) {
+func (r *BenchmarkReconciler) runControlOps(bm *cnsbench.Benchmark, rateCh chan int, done chan<- int, controlCh chan bool, rateName string) {
+	// Closed on exit, to stop any goroutines waiting for operations to
+	// complete
+	stop := make(chan struct{})
+	defer close(stop)
+	firings := 0
 	for {
 		select {
 		case <-controlCh:
@@ -413,14 +909,21 @@ func (r *BenchmarkReconciler) runControlOps(bm *cnsbench.Benchmark, rateCh chan
 			return
 		case n := <-rateCh:
 			r.Log.Info("Got rate!", "n", n)
-			r.metric(bm, "rateFired", "rateName", rateName, "n", strconv.Itoa(n))
+			firings += 1
+			r.metric(bm, "rateFired", append([]string{"rateName", rateName, "n", strconv.Itoa(n)}, firingTags(bm, rateName, n)...)...)
+			var objs opObjects
 			for _, a := range bm.Spec.Volumes {
 				if a.RateName == rateName {
-					r.CreateVolume(bm, a)
+					objs.created = append(objs.created, r.CreateVolume(bm, a)...)
 				}
 			}
 			for _, a := range bm.Spec.Workloads {
-				if a.RateName == rateName {
+				if a.RateName == rateName && a.RateMode == "target" {
+					r.setWorkloadTarget(bm, a.Name, n)
+					if err := r.SetWorkloadConcurrency(bm, a, n); err != nil {
+						r.Log.Error(err, "Setting workload concurrency")
+					}
+				} else if a.RateName == rateName {
 					if err := r.RunWorkload(bm, a, a.Name); err != nil {
 						r.Log.Error(err, "Running spec")
 					}
@@ -428,35 +931,96 @@ func (r *BenchmarkReconciler) runControlOps(bm *cnsbench.Benchmark, rateCh chan
 			}
 			for _, a := range bm.Spec.ControlOperations {
 				if a.RateName == rateName {
-					if err := r.runControlOp(bm, a, n); err != nil {
+					start := time.Now()
+					o, err := r.runControlOp(bm, a, n, firings)
+					go r.trackControlOp(bm, a, rateName, o, start, err)
+					if err != nil {
 						r.Log.Error(err, "Error running action")
+					} else {
+						objs.created = append(objs.created, o.created...)
+						objs.deleted = append(objs.deleted, o.deleted...)
 					}
 				}
 			}
+			if done != nil {
+				go r.awaitOps(objs, n, done, stop)
+			}
 		}
 	}
 }
 
-func (r *BenchmarkReconciler) runControlOp(bm *cnsbench.Benchmark, a cnsbench.ControlOperation, rateCounter int) error {
+// Objects created or deleted by a control operation, so that closed-loop
+// rates can tell when the operation has finished
+type opObjects struct {
+	created []client.Object
+	deleted []client.Object
+}
+
+// rateCounter is the value the rate fired with, and firingNum the number of
+// times it has fired so far
+func (r *BenchmarkReconciler) runControlOp(bm *cnsbench.Benchmark, a cnsbench.ControlOperation, rateCounter, firingNum int) (opObjects, error) {
 	r.Log.Info("Running action", "name", a, "deletespec", metav1.FormatLabelSelector(&a.DeleteSpec.Selector))
+	var objs opObjects
+	var err error
 	if a.SnapshotSpec.SnapshotClass != "" {
-		return r.CreateSnapshot(bm, a.SnapshotSpec, a.Name)
-	} else if metav1.FormatLabelSelector(&a.DeleteSpec.Selector) != "" &&
-		metav1.FormatLabelSelector(&a.DeleteSpec.Selector) != "<none>" {
-		return r.DeleteObj(bm, a.DeleteSpec)
+		objs.created, err = r.CreateSnapshot(bm, a)
+	} else if (metav1.FormatLabelSelector(&a.DeleteSpec.Selector) != "" &&
+		metav1.FormatLabelSelector(&a.DeleteSpec.Selector) != "<none>") ||
+		len(a.DeleteSpec.Kinds) > 0 {
+		objs.deleted, err = r.DeleteObj(bm, a)
+	} else if a.ScaleSpec.ObjName != "" && a.ScaleSpec.ScaleScripts != "" {
+		err = r.ScaleObj(bm, a, rateCounter)
 	} else if a.ScaleSpec.ObjName != "" {
-		return r.ScaleObj(bm, a.ScaleSpec, rateCounter)
+		err = r.ScaleNative(bm, a, rateCounter)
+	} else if a.ScaleSpec.WorkloadName != "" {
+		err = r.ScaleWorkload(bm, a, rateCounter)
+	} else if a.ResizeSpec != nil {
+		_, err = r.ResizeVolume(bm, a)
+	} else if a.RestoreSpec != nil {
+		objs.created, err = r.RestoreSnapshot(bm, a)
+	} else if a.CloneSpec != nil {
+		objs.created, err = r.CloneVolume(bm, a)
+	} else if a.PodKillSpec != nil {
+		objs.deleted, err = r.KillPods(bm, a)
+	} else if a.DrainSpec != nil {
+		err = r.DrainNodes(bm, a)
+	} else if a.MountChurnSpec != nil {
+		objs.deleted, err = r.ChurnMounts(bm, a)
+	} else if a.ExecSpec != nil {
+		err = r.ExecCommand(bm, a)
+	} else if a.CreateSpec.ConfigMapName != "" {
+		objs.created, err = r.CreateFromTemplate(bm, a, rateCounter, firingNum)
 	} else {
 		r.Log.Info("Unknown kind of action")
 	}
-	return nil
+	return objs, err
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *BenchmarkReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.controlChannels = make(map[string](chan bool))
 	r.workloadInstance = make(map[string]int)
-	var err error
+	r.eventRates = make(map[string][]*eventRate)
+	r.rateStats = make(map[string]map[string]*rates.Stats)
+	r.workloadTargets = make(map[string]map[string]int)
+	r.opRNGs = make(map[string]map[string]*rand.Rand)
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
+	// Delete operations look up every kind of object each time they run,
+	// so keep the results of discovery rather than asking every time
+	r.discovery = memory.NewMemCacheClient(discoveryClient)
+	r.restMapper = mgr.GetRESTMapper()
+	r.apiReader = mgr.GetAPIReader()
+	r.scaleClient, err = scale.NewForConfig(mgr.GetConfig(), r.restMapper, dynamic.LegacyAPIPathResolverFunc, scale.NewDiscoveryScaleKindResolver(discoveryClient))
+	if err != nil {
+		return err
+	}
+	r.restConfig = mgr.GetConfig()
+	if r.clientset, err = kubernetes.NewForConfig(r.restConfig); err != nil {
+		return err
+	}
 	r.controller, err = ctrl.NewControllerManagedBy(mgr).
 		For(&cnsbench.Benchmark{}).
 		Build(r)
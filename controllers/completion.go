@@ -3,15 +3,41 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
 	cnsbench "github.com/cnsbench/cnsbench/api/v1alpha1"
+	snapshotv1beta1 "github.com/kubernetes-csi/external-snapshotter/v2/pkg/apis/volumesnapshot/v1beta1"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// How long to wait for the objects a control operation touches to reach their
+// final state, and how often to check on them
+const OP_TIMEOUT = 10 * time.Minute
+const OP_POLL_INTERVAL = time.Second
+
+// waitForOp polls cond until it returns true, then returns how long it has
+// been since start.  Returns an error if cond does, or if it doesn't return
+// true within OP_TIMEOUT.
+func waitForOp(start time.Time, cond wait.ConditionFunc) (time.Duration, error) {
+	err := wait.PollImmediate(OP_POLL_INTERVAL, OP_TIMEOUT, cond)
+	return time.Since(start), err
+}
+
+// Durations in metrics are in milliseconds
+func millis(d time.Duration) string {
+	return strconv.FormatInt(d.Milliseconds(), 10)
+}
+
 func CheckInit(c client.Client, workloads []cnsbench.Workload) (bool, error) {
 	for _, a := range workloads {
 		labelSelector, err := metav1.ParseToLabelSelector("workloadname=" + a.Name)
@@ -172,3 +198,99 @@ func PVCsComplete(c client.Client, selector labels.Selector) (int, int, error) {
 	}
 	return complete, notcomplete, nil
 }
+
+// ObjsComplete returns true once every created object is ready to use (PVCs
+// are Bound, VolumeSnapshots are ReadyToUse, anything else as soon as it
+// exists) and every deleted object is gone
+func ObjsComplete(c client.Client, created, deleted []client.Object) (bool, error) {
+	for _, obj := range created {
+		key := client.ObjectKey{Name: obj.GetName(), Namespace: obj.GetNamespace()}
+		switch obj.(type) {
+		case *corev1.PersistentVolumeClaim:
+			pvc := &corev1.PersistentVolumeClaim{}
+			if err := c.Get(context.TODO(), key, pvc); err != nil {
+				return false, err
+			}
+			if pvc.Status.Phase != "Bound" {
+				return false, nil
+			}
+		case *snapshotv1beta1.VolumeSnapshot:
+			snap := &unstructured.Unstructured{}
+			snap.SetGroupVersionKind(snapshotv1beta1.SchemeGroupVersion.WithKind("VolumeSnapshot"))
+			if err := c.Get(context.TODO(), key, snap); err != nil {
+				return false, err
+			}
+			if ready, _, _ := unstructured.NestedBool(snap.Object, "status", "readyToUse"); !ready {
+				return false, nil
+			}
+		}
+	}
+
+	for _, obj := range deleted {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
+		err := c.Get(context.TODO(), client.ObjectKey{Name: obj.GetName(), Namespace: obj.GetNamespace()}, u)
+		if err == nil {
+			return false, nil
+		} else if !errors.IsNotFound(err) {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// LiveWorkloadInstances returns the instances of a workload that have not
+// completed and are not being deleted, newest first.  An instance is a Pod,
+// Job or StatefulSet with the workload role; pods belonging to one of the
+// workload's Jobs or StatefulSets are not counted separately.  c should read
+// from the API server rather than a cache when the count has to include
+// instances that were only just started or stopped.
+func LiveWorkloadInstances(c client.Reader, workloadName string) ([]client.Object, error) {
+	ls := &metav1.LabelSelector{}
+	ls = metav1.AddLabelToSelector(ls, "workloadname", workloadName)
+	ls = metav1.AddLabelToSelector(ls, "role", "workload")
+	selector, err := metav1.LabelSelectorAsSelector(ls)
+	if err != nil {
+		return nil, err
+	}
+	opts := &client.ListOptions{Namespace: "default", LabelSelector: selector}
+
+	var live []client.Object
+	pods := &corev1.PodList{}
+	if err := c.List(context.TODO(), pods, opts); err != nil {
+		return nil, err
+	}
+	for i, pod := range pods.Items {
+		if owner := metav1.GetControllerOf(&pod); owner != nil && owner.Kind != "Benchmark" {
+			continue
+		}
+		if pod.DeletionTimestamp == nil && pod.Status.Phase != "Succeeded" && pod.Status.Phase != "Failed" {
+			live = append(live, &pods.Items[i])
+		}
+	}
+	jobs := &batchv1.JobList{}
+	if err := c.List(context.TODO(), jobs, opts); err != nil {
+		return nil, err
+	}
+	for i, job := range jobs.Items {
+		if job.DeletionTimestamp == nil && (job.Spec.Completions == nil || job.Status.Succeeded < *job.Spec.Completions) {
+			live = append(live, &jobs.Items[i])
+		}
+	}
+	stss := &appsv1.StatefulSetList{}
+	if err := c.List(context.TODO(), stss, opts); err != nil {
+		return nil, err
+	}
+	for i, sts := range stss.Items {
+		if sts.DeletionTimestamp == nil {
+			live = append(live, &stss.Items[i])
+		}
+	}
+
+	sort.Slice(live, func(i, j int) bool {
+		ti, tj := live[i].GetCreationTimestamp(), live[j].GetCreationTimestamp()
+		return tj.Before(&ti)
+	})
+	return live, nil
+}
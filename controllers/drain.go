@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cnsbench "github.com/cnsbench/cnsbench/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	storagev1 "k8s.io/api/storage/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/* A drain works the same way "kubectl drain" does: the node is cordoned so
+ * nothing new is scheduled on it, then every pod on it is evicted, except for
+ * DaemonSet and static pods which would only come straight back.  Evictions
+ * respect PodDisruptionBudgets, so an eviction that is refused is retried until
+ * OP_TIMEOUT.  Each volume used by an evicted pod is tracked until it has been
+ * detached from the drained node, attached to another one, and a pod using it
+ * there is ready.
+ */
+
+// Cordons and drains the nodes running the selected pods of a workload.  The
+// drains, and the volumes moving to other nodes, are tracked in the
+// background.
+func (r *BenchmarkReconciler) DrainNodes(bm *cnsbench.Benchmark, a cnsbench.ControlOperation) error {
+	d := a.DrainSpec
+	var uncordonAfter time.Duration
+	if d.UncordonAfter != "" {
+		var err error
+		if uncordonAfter, err = time.ParseDuration(d.UncordonAfter); err != nil {
+			return err
+		}
+	}
+
+	running, _, err := r.runningPods(d.WorkloadName, d.Role)
+	if err != nil {
+		return err
+	}
+	if len(running) == 0 {
+		r.Log.Info("No running pods to drain the nodes of", "workload", d.WorkloadName)
+		return nil
+	}
+
+	var nodes []string
+	seen := make(map[string]bool)
+	for _, pod := range pickObjs(running, policyOr(d.Policy, "random"), d.Count, r.opRNG(bm, a.Name, d.Seed)) {
+		node, _, _ := unstructured.NestedString(pod.Object, "spec", "nodeName")
+		if node != "" && !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+
+	for _, node := range nodes {
+		start := time.Now()
+		if err := r.setUnschedulable(node, true); err != nil {
+			return err
+		}
+		r.Log.Info("Cordoned node", "node", node)
+		go r.drainNode(bm, a, node, start, uncordonAfter)
+	}
+	return nil
+}
+
+func (r *BenchmarkReconciler) setUnschedulable(nodeName string, unschedulable bool) error {
+	node := &corev1.Node{}
+	node.Name = nodeName
+	patch := []byte(fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable))
+	return r.Client.Patch(context.TODO(), node, client.RawPatch(types.MergePatchType, patch))
+}
+
+// Returns true if draining the node shouldn't evict pod
+func skipEviction(pod corev1.Pod) bool {
+	if _, exists := pod.Annotations[corev1.MirrorPodAnnotationKey]; exists {
+		return true
+	}
+	if owner := metav1.GetControllerOf(&pod); owner != nil && owner.Kind == "DaemonSet" {
+		return true
+	}
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+// Evicts every pod from a cordoned node, then uncordons it if uncordonAfter is
+// set
+func (r *BenchmarkReconciler) drainNode(bm *cnsbench.Benchmark, a cnsbench.ControlOperation, node string, start time.Time, uncordonAfter time.Duration) {
+	pods := &corev1.PodList{}
+	if err := r.Client.List(context.TODO(), pods); err != nil {
+		r.Log.Error(err, "Listing pods to drain", "node", node)
+		r.opMetric(bm, a, "drainNode", "node", node, "error", err.Error())
+		return
+	}
+
+	remaining := make(map[types.UID]corev1.Pod)
+	tracked := make(map[string]bool)
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != node || skipEviction(pod) {
+			continue
+		}
+		remaining[pod.UID] = pod
+		// Start tracking the volumes before they have a chance to move.
+		// Several pods on the node can share a volume.
+		for _, v := range pod.Spec.Volumes {
+			if v.PersistentVolumeClaim == nil || tracked[pod.Namespace+"/"+v.PersistentVolumeClaim.ClaimName] {
+				continue
+			}
+			tracked[pod.Namespace+"/"+v.PersistentVolumeClaim.ClaimName] = true
+			go r.trackMigration(bm, a, node, pod.Namespace, v.PersistentVolumeClaim.ClaimName, start)
+		}
+	}
+	numPods := len(remaining)
+
+	evicted := make(map[types.UID]bool)
+	latency, err := waitForOp(start, func() (bool, error) {
+		for uid, pod := range remaining {
+			if evicted[uid] {
+				current := &corev1.Pod{}
+				err := r.Client.Get(context.TODO(), client.ObjectKey{Name: pod.Name, Namespace: pod.Namespace}, current)
+				if k8serrors.IsNotFound(err) || (err == nil && current.UID != uid) {
+					delete(remaining, uid)
+				} else if err != nil {
+					return false, err
+				}
+				continue
+			}
+
+			eviction := &policyv1beta1.Eviction{
+				ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+			}
+			err := r.clientset.CoreV1().Pods(pod.Namespace).Evict(context.TODO(), eviction)
+			if err == nil {
+				evicted[uid] = true
+			} else if k8serrors.IsNotFound(err) {
+				delete(remaining, uid)
+			} else if !k8serrors.IsTooManyRequests(err) {
+				// Too many requests means a disruption budget
+				// won't allow the eviction yet
+				return false, err
+			}
+		}
+		return len(remaining) == 0, nil
+	})
+
+	metrics := []string{"node", node, "pods", fmt.Sprint(numPods), "latencyMs", millis(latency)}
+	if err != nil {
+		r.Log.Error(err, "Draining node", "node", node)
+		metrics = append(metrics, "error", err.Error())
+	}
+	r.opMetric(bm, a, "drainNode", metrics...)
+
+	if uncordonAfter == 0 {
+		return
+	}
+	time.Sleep(time.Until(start.Add(uncordonAfter)))
+	// Uncordon even if the Benchmark has since been deleted, rather than
+	// leave the node unusable
+	metrics = []string{"node", node}
+	if err := r.setUnschedulable(node, false); err != nil {
+		r.Log.Error(err, "Uncordoning node", "node", node)
+		metrics = append(metrics, "error", err.Error())
+	} else {
+		r.Log.Info("Uncordoned node", "node", node)
+	}
+	r.opMetric(bm, a, "uncordonNode", metrics...)
+}
+
+// Waits for a volume to be detached from a drained node, attached to another
+// node, and used by a ready pod there.  Volumes that don't use CSI have no
+// VolumeAttachments, so only the pod becoming ready is reported for them.
+func (r *BenchmarkReconciler) trackMigration(bm *cnsbench.Benchmark, a cnsbench.ControlOperation, fromNode, namespace, claimName string, start time.Time) {
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: claimName, Namespace: namespace}, pvc); err != nil {
+		r.Log.Error(err, "Getting volume to track", "name", claimName)
+		r.opMetric(bm, a, "volumeMigration", "pvc", claimName, "from", fromNode, "error", err.Error())
+		return
+	}
+	pvName := pvc.Spec.VolumeName
+
+	var detachLatency, attachLatency time.Duration
+	var toNode string
+	sawAttachment := false
+	latency, err := waitForOp(start, func() (bool, error) {
+		vas := &storagev1.VolumeAttachmentList{}
+		if err := r.Client.List(context.TODO(), vas); err != nil {
+			return false, err
+		}
+		detached := true
+		for _, va := range vas.Items {
+			if va.Spec.Source.PersistentVolumeName == nil || *va.Spec.Source.PersistentVolumeName != pvName {
+				continue
+			}
+			sawAttachment = true
+			if va.Spec.NodeName == fromNode {
+				detached = false
+			} else if va.Status.Attached && attachLatency == 0 {
+				attachLatency = time.Since(start)
+			}
+		}
+		if detached && detachLatency == 0 {
+			detachLatency = time.Since(start)
+		}
+
+		pods := &corev1.PodList{}
+		if err := r.Client.List(context.TODO(), pods, client.InNamespace(namespace)); err != nil {
+			return false, err
+		}
+		for _, pod := range pods.Items {
+			if pod.Spec.NodeName == fromNode || pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning || !podReady(pod) {
+				continue
+			}
+			for _, v := range pod.Spec.Volumes {
+				if v.PersistentVolumeClaim != nil && v.PersistentVolumeClaim.ClaimName == claimName {
+					toNode = pod.Spec.NodeName
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	})
+
+	metrics := []string{"pvc", claimName, "pv", pvName, "from", fromNode, "to", toNode}
+	if sawAttachment {
+		metrics = append(metrics, "detachMs", millis(detachLatency), "attachMs", millis(attachLatency))
+	}
+	metrics = append(metrics, "podReadyMs", millis(latency))
+	if err != nil {
+		r.Log.Error(err, "Waiting for volume to move", "name", claimName)
+		metrics = append(metrics, "error", err.Error())
+	}
+	r.opMetric(bm, a, "volumeMigration", metrics...)
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+
+	cnsbench "github.com/cnsbench/cnsbench/api/v1alpha1"
+	"github.com/cnsbench/cnsbench/pkg/rates"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Number of events that can be waiting for an event rate to pass them on
+// before we start dropping them
+const EVENT_QUEUE_LEN = 100
+
+type eventRate struct {
+	spec   cnsbench.EventRate
+	events chan int
+	count  int
+	// Objects we have already fired for
+	seen map[types.UID]bool
+}
+
+/* Event rates are driven by the reconciler: the objects an event rate cares
+ * about are created by createObj, which also sets up a watch on them, so any
+ * change to them causes the Benchmark to be reconciled.  When it is, we check
+ * each event rate to see if any new objects have reached the state the rate is
+ * waiting for.  Control operations finishing are reported directly by
+ * runControlOps.
+ */
+func (r *BenchmarkReconciler) createEventRate(bm *cnsbench.Benchmark, rate rates.Rate, spec cnsbench.EventRate) error {
+	r.Log.Info("Launching EventRate", "spec", spec)
+	e := &eventRate{
+		spec:   spec,
+		events: make(chan int, EVENT_QUEUE_LEN),
+		seen:   make(map[types.UID]bool),
+	}
+
+	r.eventMutex.Lock()
+	r.eventRates[bm.ObjectMeta.Name] = append(r.eventRates[bm.ObjectMeta.Name], e)
+	r.eventMutex.Unlock()
+
+	go rate.EventRate(e.events)
+	return nil
+}
+
+func (r *BenchmarkReconciler) deleteEventRates(bm *cnsbench.Benchmark) {
+	r.eventMutex.Lock()
+	delete(r.eventRates, bm.ObjectMeta.Name)
+	r.eventMutex.Unlock()
+}
+
+// Must be called with eventMutex held
+func (r *BenchmarkReconciler) fireEvent(e *eventRate) {
+	e.count += 1
+	select {
+	case e.events <- e.count:
+	default:
+		r.Log.Info("Event queue full, dropping event", "spec", e.spec)
+	}
+}
+
+// Fires every event rate waiting for the given control operation
+func (r *BenchmarkReconciler) controlOpDone(bm *cnsbench.Benchmark, opName string) {
+	r.eventMutex.Lock()
+	defer r.eventMutex.Unlock()
+	for _, e := range r.eventRates[bm.ObjectMeta.Name] {
+		if e.spec.ControlOperationName == opName {
+			r.fireEvent(e)
+		}
+	}
+}
+
+// Fires the event rates for any workload pods or volumes that have reached the
+// state the rate is waiting for since the last time we checked
+func (r *BenchmarkReconciler) checkEvents(bm *cnsbench.Benchmark) error {
+	r.eventMutex.Lock()
+	defer r.eventMutex.Unlock()
+	for _, e := range r.eventRates[bm.ObjectMeta.Name] {
+		if e.spec.WorkloadName != "" {
+			ls := &metav1.LabelSelector{}
+			ls = metav1.AddLabelToSelector(ls, "workloadname", e.spec.WorkloadName)
+			selector, err := metav1.LabelSelectorAsSelector(ls)
+			if err != nil {
+				return err
+			}
+			pods := &corev1.PodList{}
+			if err := r.Client.List(context.TODO(), pods, &client.ListOptions{Namespace: "default", LabelSelector: selector}); err != nil {
+				return err
+			}
+			for _, pod := range pods.Items {
+				if pod.Status.Phase == "Succeeded" && !e.seen[pod.UID] {
+					r.Log.Info("Workload pod succeeded, firing event", "pod", pod.Name)
+					e.seen[pod.UID] = true
+					r.fireEvent(e)
+				}
+			}
+		} else if e.spec.VolumeName != "" {
+			ls := &metav1.LabelSelector{}
+			ls = metav1.AddLabelToSelector(ls, "volumename", e.spec.VolumeName)
+			selector, err := metav1.LabelSelectorAsSelector(ls)
+			if err != nil {
+				return err
+			}
+			pvcs := &corev1.PersistentVolumeClaimList{}
+			if err := r.Client.List(context.TODO(), pvcs, &client.ListOptions{Namespace: "default", LabelSelector: selector}); err != nil {
+				return err
+			}
+			for _, pvc := range pvcs.Items {
+				if pvc.Status.Phase == "Bound" && !e.seen[pvc.UID] {
+					r.Log.Info("Volume bound, firing event", "pvc", pvc.Name)
+					e.seen[pvc.UID] = true
+					r.fireEvent(e)
+				}
+			}
+		}
+	}
+	return nil
+}
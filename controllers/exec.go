@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	cnsbench "github.com/cnsbench/cnsbench/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// Most output sinks don't want huge metrics, so command output beyond this many
+// bytes is cut off
+const EXEC_OUTPUT_LIMIT = 64 * 1024
+
+type execResult struct {
+	stdout, stderr string
+	exitCode       int
+	duration       time.Duration
+	err            error
+}
+
+// Starts a command in running pods of a workload, at the same time in each of
+// them, and returns without waiting for it to finish.  Each command's result
+// is sent as a metric in the background once it finishes or times out, so a
+// slow command doesn't hold up the other operations on the rate.
+func (r *BenchmarkReconciler) ExecCommand(bm *cnsbench.Benchmark, a cnsbench.ControlOperation) error {
+	e := a.ExecSpec
+	timeout := OP_TIMEOUT
+	if e.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(e.Timeout); err != nil {
+			return err
+		}
+	}
+
+	running, _, err := r.runningPods(e.WorkloadName, e.Role)
+	if err != nil {
+		return err
+	}
+	if len(running) == 0 {
+		r.Log.Info("No running pods to exec in", "workload", e.WorkloadName)
+		return nil
+	}
+
+	for _, pod := range pickObjs(running, policyOr(e.Policy, "all"), e.Count, r.opRNG(bm, a.Name, e.Seed)) {
+		container := e.Container
+		if container == "" {
+			containers, _, _ := unstructured.NestedSlice(pod.Object, "spec", "containers")
+			if len(containers) > 0 {
+				container, _, _ = unstructured.NestedString(containers[0].(map[string]interface{}), "name")
+			}
+		}
+
+		go func(podName, container string) {
+			res := r.execInPod(podName, container, e.Command, timeout)
+			metrics := []string{"pod", podName, "container", container, "command", strings.Join(e.Command, " "), "durationMs", millis(res.duration)}
+			if res.err != nil {
+				r.Log.Error(res.err, "Running command", "pod", podName)
+				metrics = append(metrics, "error", res.err.Error())
+			} else {
+				metrics = append(metrics, "exitCode", fmt.Sprint(res.exitCode), "stdout", res.stdout, "stderr", res.stderr)
+			}
+			r.opMetric(bm, a, "execCommand", metrics...)
+		}(pod.GetName(), container)
+	}
+	return nil
+}
+
+// Wraps an upgrader to keep hold of the connection it makes, so that a stream
+// can be cut off: the version of client-go we use can't cancel Stream.
+type closableUpgrader struct {
+	spdy.Upgrader
+	mutex  sync.Mutex
+	conn   httpstream.Connection
+	closed bool
+}
+
+func (u *closableUpgrader) NewConnection(resp *http.Response) (httpstream.Connection, error) {
+	conn, err := u.Upgrader.NewConnection(resp)
+	if err != nil {
+		return nil, err
+	}
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.closed {
+		conn.Close()
+		return nil, fmt.Errorf("Connection closed")
+	}
+	u.conn = conn
+	return conn, nil
+}
+
+// Closes the connection, or the connection once it is made if it hasn't been
+// yet, which makes Stream return
+func (u *closableUpgrader) Close() {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.closed = true
+	if u.conn != nil {
+		u.conn.Close()
+	}
+}
+
+// Runs a command in a container through the pods/exec subresource.  The
+// command isn't stopped in the container if it takes longer than the timeout,
+// we just close the connection and stop waiting for it.
+func (r *BenchmarkReconciler) execInPod(podName, container string, command []string, timeout time.Duration) execResult {
+	req := r.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(podName).
+		Namespace("default").
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	transport, upgrader, err := spdy.RoundTripperFor(r.restConfig)
+	if err != nil {
+		return execResult{err: err}
+	}
+	conn := &closableUpgrader{Upgrader: upgrader}
+	executor, err := remotecommand.NewSPDYExecutorForTransports(transport, conn, "POST", req.URL())
+	if err != nil {
+		return execResult{err: err}
+	}
+
+	done := make(chan execResult, 1)
+	start := time.Now()
+	go func() {
+		var stdout, stderr bytes.Buffer
+		err := executor.Stream(remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr})
+		res := execResult{
+			stdout:   truncate(stdout.String(), EXEC_OUTPUT_LIMIT),
+			stderr:   truncate(stderr.String(), EXEC_OUTPUT_LIMIT),
+			duration: time.Since(start),
+		}
+		if exitErr, ok := err.(utilexec.ExitError); ok && exitErr.Exited() {
+			res.exitCode = exitErr.ExitStatus()
+		} else {
+			res.err = err
+		}
+		done <- res
+	}()
+
+	select {
+	case res := <-done:
+		return res
+	case <-time.After(timeout):
+		conn.Close()
+		return execResult{duration: time.Since(start), err: fmt.Errorf("Command in %s did not finish within %s", podName, timeout)}
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	cnsbench "github.com/cnsbench/cnsbench/api/v1alpha1"
+
+	snapshotv1beta1 "github.com/kubernetes-csi/external-snapshotter/v2/pkg/apis/volumesnapshot/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apiserver/pkg/storage/names"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+ * A group snapshot takes a snapshot of every volume of a workload while the
+ * workload is quiesced, so that the snapshots are consistent with each other
+ * (e.g., the data and log volumes of a database).  The operation goes:
+ *
+ *   1. Quiesce the workload, either by running a command in each of its pods
+ *      or by scaling it to zero replicas
+ *   2. Create a snapshot of each volume, all labelled with the group's name
+ *   3. Wait for every snapshot to be cut, i.e. to have a creation time
+ *   4. Resume the workload, even if one of the previous steps failed
+ *
+ * The whole thing is reported as a single groupSnapshot metric with the length
+ * of the quiesce window, and each snapshot is also tracked until it is ready
+ * to use, like any other snapshot.
+ */
+
+// Quiesces a workload, snapshots all of its volumes, and resumes it.  Returns
+// the VolumeSnapshots that were created.
+func (r *BenchmarkReconciler) CreateGroupSnapshot(bm *cnsbench.Benchmark, a cnsbench.ControlOperation) ([]client.Object, error) {
+	s := a.SnapshotSpec
+	if s.WorkloadName == "" {
+		return nil, fmt.Errorf("Group snapshot %s must set workloadName", a.Name)
+	}
+
+	pvcs, err := r.selectPVCs(s.WorkloadName, s.VolumeName)
+	if err != nil {
+		return nil, err
+	}
+	if len(pvcs.Items) == 0 {
+		return nil, fmt.Errorf("No volumes to snapshot for workload %s", s.WorkloadName)
+	}
+
+	group := names.NameGenerator.GenerateName(names.SimpleNameGenerator, bm.ObjectMeta.Name+"-group-")
+	quiesce := s.Quiesce
+	if quiesce == "" {
+		quiesce = "none"
+	}
+
+	start := time.Now()
+	resume, err := r.quiesceWorkload(bm, a)
+	quiesced := time.Now()
+
+	var created []client.Object
+	var snapTimes []string
+	if err == nil {
+		for _, pvc := range pvcs.Items {
+			snapStart := time.Now()
+			snap, serr := r.snapshotPVC(bm, a, pvc.Name, map[string]string{"snapshotgroup": group})
+			if serr != nil {
+				r.Log.Error(serr, "Creating snapshot", "group", group)
+				err = serr
+				break
+			}
+			go r.trackSnapshot(bm, a, snap.Name, pvc.Name, snapStart, "group", group)
+			created = append(created, snap)
+		}
+	}
+	if err == nil {
+		snapTimes, err = r.waitForSnapshotsCut(created, quiesced)
+	}
+	snapshotted := time.Now()
+
+	// The workload gets resumed no matter what went wrong, a benchmark
+	// shouldn't be left with a frozen workload
+	var rerr error
+	if resume != nil {
+		rerr = resume()
+	}
+	end := time.Now()
+
+	snapNames := make([]string, len(created))
+	for i, snap := range created {
+		snapNames[i] = snap.GetName()
+	}
+	metrics := []string{
+		"group", group,
+		"workload", s.WorkloadName,
+		"quiesce", quiesce,
+		"snapshots", strings.Join(snapNames, ","),
+		"snapshotTimesMs", strings.Join(snapTimes, ","),
+		"quiesceMs", millis(quiesced.Sub(start)),
+		"snapshotMs", millis(snapshotted.Sub(quiesced)),
+		"resumeMs", millis(end.Sub(snapshotted)),
+		"windowMs", millis(end.Sub(start)),
+	}
+	if err == nil {
+		err = rerr
+	}
+	if err != nil {
+		r.Log.Error(err, "Group snapshot", "group", group)
+		metrics = append(metrics, "error", err.Error())
+	}
+	r.opMetric(bm, a, "groupSnapshot", metrics...)
+
+	return created, err
+}
+
+// Quiesces the workload a group snapshot is of.  Returns a function that
+// resumes the workload, which is non-nil whenever there may be something to
+// undo, even if quiescing failed partway.
+func (r *BenchmarkReconciler) quiesceWorkload(bm *cnsbench.Benchmark, a cnsbench.ControlOperation) (func() error, error) {
+	s := a.SnapshotSpec
+	switch s.Quiesce {
+	case "", "none":
+		return nil, nil
+	case "exec":
+		return r.quiesceByExec(bm, a)
+	case "scale":
+		return r.quiesceByScaling(bm, a)
+	}
+	return nil, fmt.Errorf("Unknown quiesce method %s", s.Quiesce)
+}
+
+// Runs the quiesce command in each of the workload's running pods.  The resume
+// command is run in the same pods.
+func (r *BenchmarkReconciler) quiesceByExec(bm *cnsbench.Benchmark, a cnsbench.ControlOperation) (func() error, error) {
+	s := a.SnapshotSpec
+	if len(s.QuiesceCommand) == 0 {
+		return nil, fmt.Errorf("Group snapshot %s must set quiesceCommand to quiesce with exec", a.Name)
+	}
+
+	pods, _, err := r.runningPods(s.WorkloadName, "workload")
+	if err != nil {
+		return nil, err
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("No running pods to quiesce for workload %s", s.WorkloadName)
+	}
+
+	resume := func() error {
+		if len(s.ResumeCommand) == 0 {
+			return nil
+		}
+		return r.execInPods(pods, s.ResumeCommand)
+	}
+	return resume, r.execInPods(pods, s.QuiesceCommand)
+}
+
+// Runs a command in the first container of each pod, at the same time in each
+// of them.  Unlike the exec operation, the command exiting with a non-zero
+// code is an error.
+func (r *BenchmarkReconciler) execInPods(pods []unstructured.Unstructured, command []string) error {
+	errs := make([]error, len(pods))
+	var wg sync.WaitGroup
+	for i, pod := range pods {
+		container := ""
+		containers, _, _ := unstructured.NestedSlice(pod.Object, "spec", "containers")
+		if len(containers) > 0 {
+			container, _, _ = unstructured.NestedString(containers[0].(map[string]interface{}), "name")
+		}
+
+		wg.Add(1)
+		go func(i int, podName, container string) {
+			defer wg.Done()
+			res := r.execInPod(podName, container, command, OP_TIMEOUT)
+			if res.err != nil {
+				errs[i] = res.err
+			} else if res.exitCode != 0 {
+				errs[i] = fmt.Errorf("%s in %s exited with %d: %s", strings.Join(command, " "), podName, res.exitCode, res.stderr)
+			}
+		}(i, pod.GetName(), container)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Scales the workload to zero replicas and waits for its pods to go away.
+// Resuming scales it back to the number of replicas it had.
+func (r *BenchmarkReconciler) quiesceByScaling(bm *cnsbench.Benchmark, a cnsbench.ControlOperation) (func() error, error) {
+	s := a.SnapshotSpec
+	cm, gvk, objs, err := r.workloadScaleTargets(bm, s.WorkloadName)
+	if err != nil {
+		return nil, err
+	}
+	if cm.ObjectMeta.Annotations["scaleScripts"] != "" {
+		return nil, fmt.Errorf("Workload %s is scaled with scripts and can't be quiesced by scaling", s.WorkloadName)
+	}
+	mapping, err := r.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+	gr := mapping.Resource.GroupResource()
+	scales := r.scaleClient.Scales("default")
+
+	scaleOp := a
+	scaleObj := func(name string, numReplicas int32) error {
+		scaleOp.ScaleSpec = cnsbench.Scale{ObjName: name, APIVersion: gvk.GroupVersion().String(), Kind: gvk.Kind}
+		return r.ScaleNative(bm, scaleOp, int(numReplicas))
+	}
+
+	replicas := map[string]int32{}
+	resume := func() error {
+		var rerr error
+		for name, n := range replicas {
+			if err := scaleObj(name, n); err != nil {
+				r.Log.Error(err, "Resuming workload", "name", name)
+				rerr = err
+			}
+		}
+		return rerr
+	}
+
+	for _, obj := range objs {
+		sc, err := scales.Get(context.TODO(), gr, obj.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return resume, err
+		}
+		replicas[obj.GetName()] = sc.Spec.Replicas
+		if err := scaleObj(obj.GetName(), 0); err != nil {
+			return resume, err
+		}
+	}
+
+	_, err = waitForOp(time.Now(), func() (bool, error) {
+		for name := range replicas {
+			sc, err := scales.Get(context.TODO(), gr, name, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			if sc.Status.Replicas != 0 {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	return resume, err
+}
+
+// Waits for each snapshot to be cut, which is when the snapshot gets a
+// creation time, and returns how long after start each one was seen to be cut.
+// The creation time itself only has a resolution of seconds.
+func (r *BenchmarkReconciler) waitForSnapshotsCut(snaps []client.Object, start time.Time) ([]string, error) {
+	times := make([]string, len(snaps))
+	for i, s := range snaps {
+		var cut time.Time
+		_, err := waitForOp(start, func() (bool, error) {
+			snap := &unstructured.Unstructured{}
+			snap.SetGroupVersionKind(snapshotv1beta1.SchemeGroupVersion.WithKind("VolumeSnapshot"))
+			if err := r.Client.Get(context.TODO(), client.ObjectKey{Name: s.GetName(), Namespace: "default"}, snap); err != nil {
+				return false, err
+			}
+			created, _, _ := unstructured.NestedString(snap.Object, "status", "creationTime")
+			ready, _, _ := unstructured.NestedBool(snap.Object, "status", "readyToUse")
+			if created == "" && !ready {
+				return false, nil
+			}
+			cut = time.Now()
+			return true, nil
+		})
+		if err != nil {
+			return times, err
+		}
+		times[i] = millis(cut.Sub(start))
+	}
+	return times, nil
+}
@@ -0,0 +1,91 @@
+package rates
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// A Policy decides what happens to a firing when the rate's consumer is still
+// busy with the previous one
+type Policy string
+
+const (
+	// Discard the firing
+	DropPolicy Policy = "drop"
+	// Hold on to up to QueueLength firings and pass them on, in order, as
+	// the consumer frees up
+	QueuePolicy Policy = "queue"
+	// Hold on to only the most recent firing, replacing any that was
+	// already waiting
+	CoalescePolicy Policy = "coalesce"
+)
+
+type firing struct {
+	n  int
+	at time.Time
+}
+
+func (r Rate) drop(n int) {
+	atomic.AddInt64(&r.Stats.dropped, 1)
+	atomic.AddInt64(&r.Stats.accepted, -1)
+	r.metric("rateDropped", "n", strconv.Itoa(n), "policy", string(r.Policy))
+}
+
+// forward passes firings received on in to the consumer, holding on to them
+// according to the rate's policy while the consumer is busy.  Exits when in is
+// closed.
+func (r Rate) forward(in chan firing) {
+	var queue []firing
+	for {
+		// out is nil, and so never ready, unless there is something queued
+		var out chan int
+		var next firing
+		if len(queue) > 0 {
+			out = r.Consumer
+			next = queue[0]
+		}
+
+		select {
+		case f, ok := <-in:
+			if !ok {
+				for _, f := range queue {
+					r.drop(f.n)
+				}
+				return
+			}
+			queue = r.hold(queue, f)
+			r.forwarded <- true
+		case out <- next.n:
+			queue = queue[1:]
+			atomic.AddInt64(&r.Stats.firings, 1)
+			atomic.AddInt64(&r.Stats.delayed, 1)
+			delay := time.Since(next.at)
+			r.metric("rateDelayed", "n", strconv.Itoa(next.n), "policy", string(r.Policy), "delayMs", strconv.FormatInt(delay.Milliseconds(), 10))
+		}
+	}
+}
+
+// hold passes f on to the consumer if it is free and nothing is waiting, or
+// else queues or drops firings according to the rate's policy.  Returns the
+// new queue.
+func (r Rate) hold(queue []firing, f firing) []firing {
+	if len(queue) == 0 {
+		select {
+		case r.Consumer <- f.n:
+			atomic.AddInt64(&r.Stats.firings, 1)
+			return queue
+		default:
+		}
+	}
+	if r.Policy == CoalescePolicy && len(queue) > 0 {
+		r.drop(queue[0].n)
+		queue[0] = f
+	} else if r.Policy == QueuePolicy && len(queue) >= r.QueueLength {
+		log.Info("Rate queue full", "queueLength", r.QueueLength)
+		r.drop(f.n)
+	} else {
+		queue = append(queue, f)
+	}
+	return queue
+}
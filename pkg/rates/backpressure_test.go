@@ -0,0 +1,147 @@
+package rates
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// manualTimer fires once for every value sent to it
+type manualTimer chan int
+
+func (t manualTimer) Run(tick chan<- int, stop <-chan bool) {
+	for {
+		select {
+		case n := <-t:
+			select {
+			case tick <- n:
+			case <-stop:
+				return
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// startManualRate runs a SingleRate driven by a manualTimer.  Nothing receives
+// from the rate's consumer unless the test does, so the consumer is busy
+// until then.
+func startManualRate(t *testing.T, policy Policy, queueLength, maxFirings int) (Rate, manualTimer) {
+	r := Rate{
+		Consumer:       make(chan int),
+		ControlChannel: make(chan bool),
+		Policy:         policy,
+		QueueLength:    queueLength,
+		MaxFirings:     maxFirings,
+		Stats:          &Stats{},
+	}
+	timer := make(manualTimer)
+	go r.SingleRate(timer)
+	t.Cleanup(func() { close(r.ControlChannel) })
+	return r, timer
+}
+
+func (timer manualTimer) fire(t *testing.T, n int) {
+	t.Helper()
+	select {
+	case timer <- n:
+	case <-time.After(time.Second):
+		t.Fatalf("Rate stopped taking firings at %d", n)
+	}
+}
+
+func receive(t *testing.T, r Rate, want int) {
+	t.Helper()
+	select {
+	case n := <-r.Consumer:
+		if n != want {
+			t.Errorf("Consumer got %d, want %d", n, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Consumer got nothing, want %d", want)
+	}
+}
+
+// waitForStats waits for the rate to have passed on and dropped the given
+// numbers of firings, and checks how many it has accepted
+func waitForStats(t *testing.T, r Rate, firings, dropped, accepted int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for r.Stats.Firings() != firings || r.Stats.Dropped() != dropped {
+		if time.Now().After(deadline) {
+			t.Fatalf("Got %d firings and %d dropped, want %d and %d", r.Stats.Firings(), r.Stats.Dropped(), firings, dropped)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := int(atomic.LoadInt64(&r.Stats.accepted)); got != accepted {
+		t.Errorf("Got %d accepted, want %d", got, accepted)
+	}
+}
+
+func TestDropPolicy(t *testing.T) {
+	r, timer := startManualRate(t, DropPolicy, 0, 0)
+	timer.fire(t, 1)
+	timer.fire(t, 2)
+	waitForStats(t, r, 0, 2, 0)
+	if r.Stats.Delayed() != 0 {
+		t.Errorf("Got %d delayed, want 0", r.Stats.Delayed())
+	}
+}
+
+func TestQueuePolicy(t *testing.T) {
+	r, timer := startManualRate(t, QueuePolicy, 2, 0)
+	timer.fire(t, 1)
+	timer.fire(t, 2)
+	// The queue is full, so this one is dropped
+	timer.fire(t, 3)
+	waitForStats(t, r, 0, 1, 2)
+
+	receive(t, r, 1)
+	receive(t, r, 2)
+	waitForStats(t, r, 2, 1, 2)
+	if r.Stats.Delayed() != 2 {
+		t.Errorf("Got %d delayed, want 2", r.Stats.Delayed())
+	}
+}
+
+func TestCoalescePolicy(t *testing.T) {
+	r, timer := startManualRate(t, CoalescePolicy, 0, 0)
+	timer.fire(t, 1)
+	timer.fire(t, 2)
+	timer.fire(t, 3)
+	// Each firing replaces the one waiting before it
+	waitForStats(t, r, 0, 2, 1)
+
+	receive(t, r, 3)
+	waitForStats(t, r, 1, 2, 1)
+	if r.Stats.Delayed() != 1 {
+		t.Errorf("Got %d delayed, want 1", r.Stats.Delayed())
+	}
+}
+
+func TestQueueMaxFirings(t *testing.T) {
+	r, timer := startManualRate(t, QueuePolicy, 1, 2)
+	timer.fire(t, 1)
+	// Dropped firings don't count towards MaxFirings
+	timer.fire(t, 2)
+	timer.fire(t, 3)
+	waitForStats(t, r, 0, 2, 1)
+	if r.Stats.Done() {
+		t.Fatalf("Rate done after one firing was accepted, want 2")
+	}
+
+	receive(t, r, 1)
+	timer.fire(t, 4)
+	deadline := time.Now().Add(time.Second)
+	for !r.Stats.Done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Rate not done after MaxFirings")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Queued firings are still passed on once MaxFirings is reached
+	receive(t, r, 4)
+	waitForStats(t, r, 2, 2, 2)
+}
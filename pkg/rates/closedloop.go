@@ -0,0 +1,58 @@
+package rates
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ClosedLoopRate keeps concurrency firings outstanding at once.  It fires
+// concurrency times straight away, then once more each time the consumer
+// reports on done that a firing's work has finished, after waiting
+// thinkTime.  Like EventRate, firings are never dropped and the rate's Policy
+// is ignored.
+func (r Rate) ClosedLoopRate(concurrency int, thinkTime time.Duration, done <-chan int) {
+	r.Policy = DropPolicy
+	if !r.begin("ClosedLoopRate") {
+		return
+	}
+	defer r.end()
+
+	// Each value on ready is a firing we are free to send.  At most
+	// concurrency of them are ever outstanding, so sends never block.
+	ready := make(chan bool, concurrency)
+	for i := 0; i < concurrency; i++ {
+		ready <- true
+	}
+
+	n := 0
+	for {
+		select {
+		case <-r.ControlChannel:
+			log.Info("Exiting ClosedLoopRate")
+			return
+		case <-r.deadline:
+			r.idle("ClosedLoopRate")
+			return
+		case <-done:
+			if thinkTime > 0 {
+				time.AfterFunc(thinkTime, func() { ready <- true })
+			} else {
+				ready <- true
+			}
+		case <-ready:
+			n += 1
+			select {
+			case r.Consumer <- n:
+				atomic.AddInt64(&r.Stats.firings, 1)
+				atomic.AddInt64(&r.Stats.accepted, 1)
+			case <-r.ControlChannel:
+				log.Info("Exiting ClosedLoopRate")
+				return
+			}
+			if r.exhausted() {
+				r.idle("ClosedLoopRate")
+				return
+			}
+		}
+	}
+}
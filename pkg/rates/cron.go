@@ -0,0 +1,217 @@
+package rates
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed standard 5-field cron expression: minute, hour, day
+// of month, month and day of week.  Each field is a bitmask of the values it
+// matches.
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// Set if the day of month or day of week field starts with "*"
+	domStar, dowStar bool
+}
+
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+var cronFields = []cronField{
+	{0, 59, nil},
+	{0, 23, nil},
+	{1, 31, nil},
+	{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	// 7 is also Sunday
+	{0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// ParseCron parses a standard 5-field cron expression.  Fields may be "*",
+// a value, a range "a-b", or a comma-separated list of these, and each may be
+// followed by a step "/n".  Months and days of the week may also be given by
+// their three-letter English names.
+func ParseCron(spec string) (CronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return CronSchedule{}, errors.New("Cron expression must have 5 fields: " + spec)
+	}
+
+	var masks [5]uint64
+	for i, field := range fields {
+		mask, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return CronSchedule{}, errors.New("Invalid cron field " + strconv.Quote(field) + ": " + err.Error())
+		}
+		masks[i] = mask
+	}
+	// Sunday can be either 0 or 7
+	if masks[4]&(1<<7) != 0 {
+		masks[4] |= 1
+	}
+
+	return CronSchedule{
+		minute:  masks[0],
+		hour:    masks[1],
+		dom:     masks[2],
+		month:   masks[3],
+		dow:     masks[4],
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func parseCronField(field string, f cronField) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return 0, errors.New("bad step")
+			}
+			part = part[:i]
+		}
+
+		var lo, hi int
+		if part == "*" {
+			lo, hi = f.min, f.max
+		} else {
+			var err error
+			bounds := strings.SplitN(part, "-", 2)
+			if lo, err = cronValue(bounds[0], f); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = cronValue(bounds[1], f); err != nil {
+					return 0, err
+				}
+			} else if step != 1 {
+				// "a/n" means from a to the end of the range
+				hi = f.max
+			}
+			if hi < lo {
+				return 0, errors.New("range end before start")
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+func cronValue(s string, f cronField) (int, error) {
+	if v, exists := f.names[strings.ToLower(s)]; exists {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.New("bad value " + s)
+	}
+	if v < f.min || v > f.max {
+		return 0, errors.New("value " + s + " out of range")
+	}
+	return v, nil
+}
+
+func (s CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	// As in Vixie cron, if both day fields are restricted a day matching
+	// either of them is enough
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Next returns the first time after t that matches the schedule, or the zero
+// time if nothing matches within the next five years (e.g. "0 0 30 2 *").
+func (s CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// CronTimer fires whenever one of its schedules matches, in the controller's
+// local time zone.  Each tick carries the 1-based index of the schedule that
+// fired, so consumers can tell the schedules apart.  If several schedules
+// match the same minute, each of them fires.
+type CronTimer struct {
+	Schedules []CronSchedule
+}
+
+func (t CronTimer) Run(tick chan<- int, stop <-chan bool) {
+	next := make([]time.Time, len(t.Schedules))
+	now := time.Now()
+	for i, s := range t.Schedules {
+		next[i] = s.Next(now)
+	}
+
+	for {
+		var earliest time.Time
+		for _, n := range next {
+			if !n.IsZero() && (earliest.IsZero() || n.Before(earliest)) {
+				earliest = n
+			}
+		}
+		if earliest.IsZero() {
+			// None of the schedules will ever fire again
+			<-stop
+			log.Info("Exiting Run")
+			return
+		}
+
+		timer := time.NewTimer(time.Until(earliest))
+		select {
+		case <-stop:
+			timer.Stop()
+			log.Info("Exiting Run")
+			return
+		case <-timer.C:
+		}
+
+		for i, n := range next {
+			if !n.Equal(earliest) {
+				continue
+			}
+			select {
+			case tick <- i + 1:
+			case <-stop:
+				log.Info("Exiting Run")
+				return
+			}
+			next[i] = t.Schedules[i].Next(earliest)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package rates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	at := func(s string) time.Time {
+		t, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+
+	tests := []struct {
+		name, spec, from, want string
+	}{
+		{"every hour", "15 * * * *", "2021-03-01 10:20", "2021-03-01 11:15"},
+		{"same minute is not next", "15 * * * *", "2021-03-01 10:15", "2021-03-01 11:15"},
+		{"step from value", "5/20 * * * *", "2021-03-01 10:00", "2021-03-01 10:05"},
+		{"step from value later", "5/20 * * * *", "2021-03-01 10:06", "2021-03-01 10:25"},
+		{"step from value wraps", "5/20 * * * *", "2021-03-01 10:46", "2021-03-01 11:05"},
+		{"step over range", "0 8-18/5 * * *", "2021-03-01 13:01", "2021-03-01 18:00"},
+		{"list", "0 6,18 * * *", "2021-03-01 07:00", "2021-03-01 18:00"},
+		{"month name", "0 12 * feb sun", "2021-01-01 00:00", "2021-02-07 12:00"},
+		{"upper case name", "0 0 * * MON", "2021-01-01 00:00", "2021-01-04 00:00"},
+		{"day of week range to 7", "0 0 * * 6-7", "2021-01-04 00:00", "2021-01-09 00:00"},
+		{"day of week range to 7 includes sunday", "0 0 * * 6-7", "2021-01-09 00:01", "2021-01-10 00:00"},
+		{"7 is sunday", "0 0 * * 7", "2021-01-01 00:00", "2021-01-03 00:00"},
+		{"0 is sunday", "0 0 * * 0", "2021-01-01 00:00", "2021-01-03 00:00"},
+		// Both day fields restricted: either one matching is enough
+		{"day of month or week, week first", "0 0 13 * fri", "2021-01-01 00:00", "2021-01-08 00:00"},
+		{"day of month or week, month first", "0 0 13 * fri", "2021-01-09 00:00", "2021-01-13 00:00"},
+		// A day field starting with "*" counts as unrestricted, so both
+		// have to match
+		{"starred step day of month", "0 0 */10 * mon", "2021-01-01 00:00", "2021-01-11 00:00"},
+		{"leap day", "0 0 29 2 *", "2021-01-01 00:00", "2024-02-29 00:00"},
+		{"year end", "0 0 1 1 *", "2021-12-31 23:59", "2022-01-01 00:00"},
+		{"impossible date", "0 0 30 2 *", "2021-01-01 00:00", ""},
+	}
+	for _, test := range tests {
+		s, err := ParseCron(test.spec)
+		if err != nil {
+			t.Errorf("%s: ParseCron(%q) failed: %v", test.name, test.spec, err)
+			continue
+		}
+		var want time.Time
+		if test.want != "" {
+			want = at(test.want)
+		}
+		if got := s.Next(at(test.from)); !got.Equal(want) {
+			t.Errorf("%s: Next(%s) of %q = %v, want %v", test.name, test.from, test.spec, got, want)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	specs := []string{
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"0 0 0 * *",
+		"0 0 * 13 *",
+		"0 0 * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"0 0 * * sat-sun",
+		"* * * foo *",
+		"a * * * *",
+	}
+	for _, spec := range specs {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", spec)
+		}
+	}
+}
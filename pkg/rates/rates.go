@@ -1,6 +1,8 @@
 package rates
 
 import (
+	"math/rand"
+	"sync/atomic"
 	"time"
 
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -8,87 +10,334 @@ import (
 
 var log = logf.Log.WithName("rates")
 
+// A Timer sends on tick every time it fires, until stop is closed.  Timers
+// schedule firings against the monotonic clock, so slow receivers delay a
+// firing but do not shift the ones after it.
 type Timer interface {
-	Run(chan int)
+	Run(tick chan<- int, stop <-chan bool)
+}
+
+// fire blocks until either the tick is received or the timer is stopped.
+// Returns false if the timer was stopped.
+func fire(tick chan<- int, stop <-chan bool) bool {
+	select {
+	case tick <- 1:
+		return true
+	case <-stop:
+		return false
+	}
 }
 
 type ConstTimer struct {
-	Interval int
+	Interval time.Duration
 }
 
-func (t ConstTimer) Run(pin chan int) {
-	count := 0
+func (t ConstTimer) Run(tick chan<- int, stop <-chan bool) {
+	ticker := time.NewTicker(t.Interval)
+	defer ticker.Stop()
 	for {
 		select {
-		case <-pin:
+		case <-stop:
 			log.Info("Exiting Run")
 			return
-		default:
-			if count == t.Interval {
-				count = 0
-				pin <- 1
+		case <-ticker.C:
+			if !fire(tick, stop) {
+				log.Info("Exiting Run")
+				return
+			}
+		}
+	}
+}
+
+// PoissonTimer fires with exponentially distributed inter-arrival times, so
+// the firings form a Poisson process with a mean interval of Interval
+type PoissonTimer struct {
+	Interval time.Duration
+	Seed     int64
+}
+
+func (t PoissonTimer) Run(tick chan<- int, stop <-chan bool) {
+	seed := t.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	// Each arrival is scheduled relative to the previous one rather than to
+	// when we got around to waiting for it
+	next := time.Now().Add(time.Duration(rng.ExpFloat64() * float64(t.Interval)))
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			log.Info("Exiting Run")
+			return
+		case <-timer.C:
+			if !fire(tick, stop) {
+				log.Info("Exiting Run")
+				return
 			}
-			time.Sleep(time.Second)
-			count += 1
+			next = next.Add(time.Duration(rng.ExpFloat64() * float64(t.Interval)))
+			timer.Reset(time.Until(next))
 		}
 	}
 }
 
+// Stats counts what a rate has done.  It is safe to read while the rate is
+// running.
+type Stats struct {
+	firings int64
+	dropped int64
+	delayed int64
+
+	// Firings that have been passed on or are waiting to be
+	accepted int64
+
+	// Set to 1 once the rate has stopped firing for good
+	done int32
+}
+
+// Number of firings that were passed on to the consumer
+func (s *Stats) Firings() int {
+	return int(atomic.LoadInt64(&s.firings))
+}
+
+// Number of firings that were never passed on to the consumer
+func (s *Stats) Dropped() int {
+	return int(atomic.LoadInt64(&s.dropped))
+}
+
+// Number of firings that had to wait for the consumer before being passed on
+func (s *Stats) Delayed() int {
+	return int(atomic.LoadInt64(&s.delayed))
+}
+
+// True once the rate will not fire again, because it reached its StopAfter
+// time or MaxFirings.  A rate whose timer simply runs out of firings, e.g. a
+// trace that doesn't loop, isn't done until it is stopped.
+func (s *Stats) Done() bool {
+	return atomic.LoadInt32(&s.done) == 1
+}
+
 type Rate struct {
 	Consumer       chan int
 	ControlChannel chan bool
+
+	// Optional, called to report events such as stage transitions
+	Metric func(metricType string, metrics ...string)
+
+	// Optional bounds on when and how often the rate fires.  StartAfter and
+	// StopAfter are measured from when the rate is started.  Zero means no
+	// bound.
+	StartAfter time.Duration
+	StopAfter  time.Duration
+	MaxFirings int
+
+	// What to do with a firing when the consumer is still busy with the
+	// previous one.  Defaults to DropPolicy.  QueueLength is only used by
+	// QueuePolicy.
+	Policy      Policy
+	QueueLength int
+
+	// Optional, updated as the rate runs
+	Stats *Stats
+
+	deadline <-chan time.Time
+	// Firings waiting for the consumer, if the policy isn't DropPolicy
+	pending chan firing
+	// Receives once forward has queued, passed on or dropped each firing
+	// sent on pending, so that send sees the firing's effect on the stats
+	forwarded chan bool
+}
+
+func (r Rate) metric(metricType string, metrics ...string) {
+	if r.Metric != nil {
+		r.Metric(metricType, metrics...)
+	}
+}
+
+// begin waits until the rate should start firing and sets up the rate's
+// deadline.  Returns false if the rate should not fire at all, in which case
+// it has already exited.
+func (r *Rate) begin(name string) bool {
+	if r.Stats == nil {
+		r.Stats = &Stats{}
+	}
+	if r.StopAfter > 0 {
+		r.deadline = time.After(r.StopAfter)
+	}
+	if r.Policy == QueuePolicy || r.Policy == CoalescePolicy {
+		r.pending = make(chan firing)
+		r.forwarded = make(chan bool)
+		go r.forward(r.pending)
+	}
+	if r.StartAfter > 0 {
+		log.Info(name+" waiting to start", "startAfter", r.StartAfter)
+		select {
+		case <-r.ControlChannel:
+			r.end()
+			log.Info("Exiting " + name)
+			return false
+		case <-r.deadline:
+			r.idle(name)
+			r.end()
+			return false
+		case <-time.After(r.StartAfter):
+		}
+	}
+	return true
+}
+
+// end cleans up after the rate once it has been told to exit
+func (r Rate) end() {
+	if r.pending != nil {
+		close(r.pending)
+	}
+}
+
+// idle waits for the rate to be told to exit.  A rate that is done firing
+// still has to do this, since the controller stops all of a benchmark's rates
+// the same way.
+func (r Rate) idle(name string) {
+	atomic.StoreInt32(&r.Stats.done, 1)
+	log.Info(name+" done firing", "firings", r.Stats.Firings())
+	<-r.ControlChannel
+	log.Info("Exiting " + name)
+}
+
+func (r Rate) exhausted() bool {
+	return r.MaxFirings > 0 && int(atomic.LoadInt64(&r.Stats.accepted)) >= r.MaxFirings
+}
+
+// send passes n on to the consumer.  If the consumer is still busy with the
+// previous firing, what happens to n depends on the rate's policy.  Returns
+// false once the rate has fired MaxFirings times.
+func (r Rate) send(n int) bool {
+	atomic.AddInt64(&r.Stats.accepted, 1)
+	if r.pending != nil {
+		r.pending <- firing{n: n, at: time.Now()}
+		<-r.forwarded
+	} else {
+		select {
+		case r.Consumer <- n:
+			atomic.AddInt64(&r.Stats.firings, 1)
+		default:
+			log.Info("Could not send to consumer")
+			r.drop(n)
+		}
+	}
+	return !r.exhausted()
+}
+
+// startTimer runs t in a new goroutine, returning the channel it ticks on and
+// the channel that stops it
+func startTimer(t Timer) (chan int, chan bool) {
+	tick := make(chan int)
+	stop := make(chan bool)
+	go t.Run(tick, stop)
+	return tick, stop
 }
 
 func (r Rate) SingleRate(t Timer) {
-	pin := make(chan int)
-	go t.Run(pin)
+	if !r.begin("SingleRate") {
+		return
+	}
+	defer r.end()
+	tick, stop := startTimer(t)
 	for {
 		select {
 		case <-r.ControlChannel:
-			pin <- 1
+			close(stop)
 			log.Info("Exiting SingleRate")
 			return
-		case n := <-pin:
-			select {
-			case r.Consumer <- n:
-			default:
-				log.Info("Could not send to consumer")
+		case <-r.deadline:
+			close(stop)
+			r.idle("SingleRate")
+			return
+		case n := <-tick:
+			if !r.send(n) {
+				close(stop)
+				r.idle("SingleRate")
+				return
 			}
 		}
 	}
 }
 
 func (r Rate) IncDecRate(incT Timer, decT Timer, min int, max int) {
-	pin := make(chan int)
+	if !r.begin("IncDecRate") {
+		return
+	}
+	defer r.end()
 	goingUp := true
 	counter := min
-	go incT.Run(pin)
+	tick, stop := startTimer(incT)
 	for {
 		select {
 		case <-r.ControlChannel:
-			pin <- 1
+			close(stop)
 			log.Info("Exiting IncDecRate")
 			return
-		case <-pin: // enter this case whenever timer ticks
+		case <-r.deadline:
+			close(stop)
+			r.idle("IncDecRate")
+			return
+		case <-tick: // enter this case whenever timer ticks
 			if goingUp {
 				counter += 1
 				if counter == max {
 					goingUp = false
-					pin <- 1         // stop inc timer
-					go decT.Run(pin) // start dec timer
+					close(stop)                   // stop inc timer
+					tick, stop = startTimer(decT) // start dec timer
 				}
 			} else {
 				counter -= 1
 				if counter == min {
 					goingUp = true
-					pin <- 1         // stop dec timer
-					go incT.Run(pin) // start inc timer
+					close(stop)                   // stop dec timer
+					tick, stop = startTimer(incT) // start inc timer
 				}
 			}
+			if !r.send(counter) {
+				close(stop)
+				r.idle("IncDecRate")
+				return
+			}
+		}
+	}
+}
+
+// EventRate fires once for every value received on events.  Unlike the
+// timer-based rates, firings are never dropped and the rate's Policy is
+// ignored: if the consumer is busy the rate waits for it, and events queue up
+// on the events channel instead.
+func (r Rate) EventRate(events chan int) {
+	r.Policy = DropPolicy
+	if !r.begin("EventRate") {
+		return
+	}
+	defer r.end()
+	for {
+		select {
+		case <-r.ControlChannel:
+			log.Info("Exiting EventRate")
+			return
+		case <-r.deadline:
+			r.idle("EventRate")
+			return
+		case n := <-events:
 			select {
-			case r.Consumer <- counter:
-			default:
-				log.Info("Could not sent to consumer")
+			case r.Consumer <- n:
+				atomic.AddInt64(&r.Stats.firings, 1)
+				atomic.AddInt64(&r.Stats.accepted, 1)
+			case <-r.ControlChannel:
+				log.Info("Exiting EventRate")
+				return
+			}
+			if r.exhausted() {
+				r.idle("EventRate")
+				return
 			}
 		}
 	}
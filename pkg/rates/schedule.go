@@ -0,0 +1,77 @@
+package rates
+
+import (
+	"strconv"
+	"time"
+)
+
+// A Stage is one piece of a ScheduleRate.  If Interval is set the rate fires
+// every Interval for the duration of the stage, otherwise it fires once at
+// the start of the stage with Concurrency as its value (unless Concurrency is
+// also zero, in which case the stage is idle).
+type Stage struct {
+	Duration    time.Duration
+	Interval    time.Duration
+	Concurrency int
+}
+
+func (r Rate) ScheduleRate(stages []Stage) {
+	if !r.begin("ScheduleRate") {
+		return
+	}
+	defer r.end()
+	var tick chan int
+	var stop chan bool
+
+	// Stage boundaries are computed from when the schedule started, so that
+	// time spent switching stages doesn't stretch the schedule
+	stage := -1
+	stageEnd := time.Now()
+	stageTimer := time.NewTimer(0)
+	defer stageTimer.Stop()
+	for {
+		select {
+		case <-r.ControlChannel:
+			if stop != nil {
+				close(stop)
+			}
+			log.Info("Exiting ScheduleRate")
+			return
+		case <-r.deadline:
+			if stop != nil {
+				close(stop)
+			}
+			r.idle("ScheduleRate")
+			return
+		case <-stageTimer.C:
+			if stop != nil {
+				close(stop)
+				tick, stop = nil, nil
+			}
+			stage += 1
+			if stage == len(stages) {
+				// Schedule is done, the stage timer doesn't get reset so we
+				// just wait to be told to exit
+				r.metric("rateStage", "stage", "done")
+				continue
+			}
+
+			s := stages[stage]
+			stageEnd = stageEnd.Add(s.Duration)
+			stageTimer.Reset(time.Until(stageEnd))
+			r.metric("rateStage", "stage", strconv.Itoa(stage), "duration", s.Duration.String(), "interval", s.Interval.String(), "concurrency", strconv.Itoa(s.Concurrency))
+			if s.Interval != 0 {
+				tick, stop = startTimer(ConstTimer{Interval: s.Interval})
+			} else if s.Concurrency != 0 && !r.send(s.Concurrency) {
+				r.idle("ScheduleRate")
+				return
+			}
+		case n := <-tick: // tick is nil, and so never ready, outside of interval stages
+			if !r.send(n) {
+				close(stop)
+				r.idle("ScheduleRate")
+				return
+			}
+		}
+	}
+}
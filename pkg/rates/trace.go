@@ -0,0 +1,133 @@
+package rates
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TraceTimer fires at each of the given offsets from the time Run is called.
+// Offsets are multiplied by Scale (if it is non-zero) before being used.  If
+// Loop is set, the trace starts over every Period, which defaults to the last
+// offset.  A firing that would happen at the same time as the last firing of
+// the previous pass (e.g. an offset of 0 when Period is the last offset) is
+// skipped, so the loop boundary only fires once.
+type TraceTimer struct {
+	Offsets []time.Duration
+	Scale   float64
+	Loop    bool
+	Period  time.Duration
+}
+
+func (t TraceTimer) Run(tick chan<- int, stop <-chan bool) {
+	if len(t.Offsets) == 0 {
+		<-stop
+		log.Info("Exiting Run")
+		return
+	}
+
+	start := time.Now()
+	next := 0
+	timer := time.NewTimer(time.Until(start.Add(t.scaled(t.Offsets[next]))))
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			log.Info("Exiting Run")
+			return
+		case <-timer.C:
+			if !fire(tick, stop) {
+				log.Info("Exiting Run")
+				return
+			}
+			last := start.Add(t.scaled(t.Offsets[next]))
+			next += 1
+			if next == len(t.Offsets) {
+				period := t.period()
+				if !t.Loop || period <= 0 {
+					// Nothing left to fire, just wait to be stopped
+					<-stop
+					log.Info("Exiting Run")
+					return
+				}
+				start = start.Add(period)
+				next = 0
+				for next < len(t.Offsets)-1 && !start.Add(t.scaled(t.Offsets[next])).After(last) {
+					next += 1
+				}
+			}
+			timer.Reset(time.Until(start.Add(t.scaled(t.Offsets[next]))))
+		}
+	}
+}
+
+// period returns the scaled time between the starts of passes through the
+// trace
+func (t TraceTimer) period() time.Duration {
+	if t.Period != 0 {
+		return t.scaled(t.Period)
+	}
+	return t.scaled(t.Offsets[len(t.Offsets)-1])
+}
+
+// scaled returns an offset multiplied by the timer's scale
+func (t TraceTimer) scaled(d time.Duration) time.Duration {
+	if t.Scale == 0 {
+		return d
+	}
+	return time.Duration(float64(d) * t.Scale)
+}
+
+// ParseTrace reads a list of offsets, in seconds, in either "csv" or "json"
+// format.  If format is empty, it is guessed from the data.  The returned
+// offsets are sorted.
+func ParseTrace(data, format string) ([]time.Duration, error) {
+	data = strings.TrimSpace(data)
+	if format == "" {
+		if strings.HasPrefix(data, "[") {
+			format = "json"
+		} else {
+			format = "csv"
+		}
+	}
+
+	var seconds []float64
+	switch format {
+	case "json":
+		if err := json.Unmarshal([]byte(data), &seconds); err != nil {
+			return nil, err
+		}
+	case "csv":
+		for i, line := range strings.Split(data, "\n") {
+			field := strings.TrimSpace(strings.Split(line, ",")[0])
+			if field == "" {
+				continue
+			}
+			s, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				// Allow for a header line
+				if i == 0 {
+					continue
+				}
+				return nil, err
+			}
+			seconds = append(seconds, s)
+		}
+	default:
+		return nil, errors.New("Unknown trace format " + format)
+	}
+
+	offsets := make([]time.Duration, 0, len(seconds))
+	for _, s := range seconds {
+		if s < 0 {
+			return nil, errors.New("Negative offset in trace")
+		}
+		offsets = append(offsets, time.Duration(s*float64(time.Second)))
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	return offsets, nil
+}
@@ -0,0 +1,114 @@
+package rates
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTrace(t *testing.T) {
+	secs := func(s ...float64) []time.Duration {
+		offsets := make([]time.Duration, len(s))
+		for i := range s {
+			offsets[i] = time.Duration(s[i] * float64(time.Second))
+		}
+		return offsets
+	}
+
+	tests := []struct {
+		name, data, format string
+		want               []time.Duration
+		wantErr            bool
+	}{
+		{"csv", "0\n1.5\n3", "", secs(0, 1.5, 3), false},
+		{"csv header", "offset\n1\n2", "", secs(1, 2), false},
+		{"csv extra columns", "time,op\n1,create\n2,delete", "", secs(1, 2), false},
+		{"csv blank lines", "\n1\n\n2\n", "", secs(1, 2), false},
+		{"csv sorted", "3\n1\n2", "", secs(1, 2, 3), false},
+		{"csv bad line after header", "offset\n1\nx", "", nil, true},
+		{"json guessed", "[2, 0.5, 1]", "", secs(0.5, 1, 2), false},
+		{"json guessed with space", "  [1]\n", "", secs(1), false},
+		{"json format", "[1, 2]", "json", secs(1, 2), false},
+		{"json bad", "[1, ", "json", nil, true},
+		{"negative csv", "1\n-1", "", nil, true},
+		{"negative json", "[-1]", "", nil, true},
+		{"unknown format", "1", "yaml", nil, true},
+		{"empty", "", "", secs(), false},
+	}
+	for _, test := range tests {
+		got, err := ParseTrace(test.data, test.format)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: ParseTrace succeeded, want an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: ParseTrace failed: %v", test.name, err)
+		} else if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: ParseTrace = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestTraceTimer(t *testing.T) {
+	const unit = 50 * time.Millisecond
+	ms := func(m ...int) []time.Duration {
+		offsets := make([]time.Duration, len(m))
+		for i := range m {
+			offsets[i] = time.Duration(m[i]) * unit
+		}
+		return offsets
+	}
+
+	tests := []struct {
+		name  string
+		timer TraceTimer
+		// Firing times, in units, from when the timer started
+		want []time.Duration
+	}{
+		{"once", TraceTimer{Offsets: ms(0, 1, 3)}, ms(0, 1, 3)},
+		{"scaled", TraceTimer{Offsets: ms(0, 2, 4), Scale: 0.5}, ms(0, 1, 2)},
+		// The pass starting at 2 would fire at 2 again, which is skipped
+		{"loop skips boundary", TraceTimer{Offsets: ms(0, 1, 2), Loop: true}, ms(0, 1, 2, 3, 4, 5)},
+		{"loop period", TraceTimer{Offsets: ms(0, 1), Loop: true, Period: 3 * unit}, ms(0, 1, 3, 4, 6)},
+		{"loop without leading zero", TraceTimer{Offsets: ms(1, 2), Loop: true}, ms(1, 2, 3, 4, 5)},
+		{"loop scaled period", TraceTimer{Offsets: ms(0, 2), Loop: true, Period: 6 * unit, Scale: 0.5}, ms(0, 1, 3, 4)},
+	}
+	for _, test := range tests {
+		tick := make(chan int)
+		stop := make(chan bool)
+		start := time.Now()
+		go test.timer.Run(tick, stop)
+
+		// A looping timer never runs out, so only wait for an extra
+		// firing from one that doesn't loop
+		want := len(test.want)
+		if !test.timer.Loop {
+			want += 1
+		}
+		var got []time.Duration
+		timeout := time.After(test.want[len(test.want)-1] + 2*unit)
+	loop:
+		for len(got) < want {
+			select {
+			case <-tick:
+				got = append(got, time.Since(start))
+			case <-timeout:
+				break loop
+			}
+		}
+		close(stop)
+
+		if len(got) != len(test.want) {
+			t.Errorf("%s: fired at %v, want %v", test.name, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] < test.want[i] || got[i] > test.want[i]+unit/2 {
+				t.Errorf("%s: fired at %v, want %v", test.name, got, test.want)
+				break
+			}
+		}
+	}
+}